@@ -15,6 +15,7 @@ import (
 
 func main() {
 	engine := qiaomu.Default()
+	engine.Use(qiaomu.RequestID)
 	group := engine.Group("goods")
 	group.Get("/find", func(ctx *qiaomu.Context) {
 		goods := &model.Goods{Id: 1000, Name: "8082的商品"}
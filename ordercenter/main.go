@@ -23,6 +23,7 @@ import (
 
 func main() {
 	engine := qiaomu.Default()
+	engine.Use(qiaomu.RequestID)
 	client := rpc.NewHttpClient()
 	client.RegisterHttpService("goods", &service.GoodsService{})
 	createTracer, closer, err := tracer.CreateTracer("orderCenter",
@@ -49,7 +50,7 @@ func main() {
 		params["name"] = "qiaomu"
 		span := createTracer.StartSpan("find")
 		defer span.Finish()
-		session := client.Session()
+		session := client.Session().WithContext(ctx)
 		session.ReqHandler = func(req *http.Request) {
 			ext.SpanKindRPCClient.Set(span)
 			createTracer.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header))
@@ -85,7 +86,7 @@ func main() {
 		proxy := rpc.NewQueenTcpClientProxy(option)
 		params := make([]any, 1)
 		params[0] = int64(1)
-		result, err := proxy.Call(context.Background(), "goods", "Find", params)
+		result, err := proxy.Call(ctx, "goods", "Find", params)
 		//Find(1)
 		log.Println(err)
 		ctx.JSON(http.StatusOK, result)
@@ -35,12 +35,14 @@ func (a *Accounts) BasicAuth(next HandlerFunc) HandlerFunc {
 	}
 }
 
+// 认证失败时终止处理链
 func (a *Accounts) unAuthHandler(ctx *Context) {
 	if a.UnAuthHandler != nil {
+		ctx.Abort()
 		a.UnAuthHandler(ctx)
 	} else {
 		ctx.W.Header().Set("WWW-Authenticate", a.Realm)
-		ctx.W.WriteHeader(http.StatusUnauthorized)
+		ctx.AbortWithStatus(http.StatusUnauthorized)
 	}
 }
 
@@ -1,13 +1,48 @@
 package bind
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
+)
 
 type Binding interface {
 	Name() string
 	Bind(*http.Request, any) error
 }
 
+// UriBinding 绑定路由参数(路由参数不在http.Request中)
+type UriBinding interface {
+	Name() string
+	BindUri(map[string][]string, any) error
+}
+
 var (
-	JSON = jsonBinding{}
-	XML  = xmlBinding{}
+	JSON          = jsonBinding{}
+	XML           = xmlBinding{}
+	Query         = queryBinding{}
+	Form          = formBinding{}
+	FormMultipart = formMultipartBinding{}
+	Header        = headerBinding{}
+	Uri           = uriBinding{}
 )
+
+// Default 根据请求方式和Content-Type选择绑定方式：GET、HEAD、DELETE请求绑定查询参数和表单参数，
+// 其他请求按Content-Type选择JSON、XML、multipart/form-data，默认绑定表单参数
+func Default(method, contentType string) Binding {
+	if method == http.MethodGet || method == http.MethodHead || method == http.MethodDelete {
+		return Form
+	}
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	switch strings.ToLower(strings.TrimSpace(contentType)) {
+	case "application/json":
+		return JSON
+	case "application/xml", "text/xml":
+		return XML
+	case "multipart/form-data":
+		return FormMultipart
+	default:
+		return Form
+	}
+}
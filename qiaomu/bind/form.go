@@ -0,0 +1,92 @@
+package bind
+
+import (
+	"errors"
+	"net/http"
+)
+
+// 解析multipart表单时保存在内存中的最大字节数(请求已经解析过时不再重复解析)
+const defaultMemory = 32 << 20
+
+type queryBinding struct{}
+
+func (queryBinding) Name() string {
+	return "query"
+}
+
+func (queryBinding) Bind(r *http.Request, obj any) error {
+	if err := mapForm(obj, &formSource{values: r.URL.Query(), tag: "form"}); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+type formBinding struct{}
+
+func (formBinding) Name() string {
+	return "form"
+}
+
+// Bind 绑定查询参数和表单参数(application/x-www-form-urlencoded和multipart/form-data)，表单参数优先
+func (formBinding) Bind(r *http.Request, obj any) error {
+	if err := r.ParseMultipartForm(defaultMemory); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return err
+	}
+	source := &formSource{values: r.Form, tag: "form"}
+	if r.MultipartForm != nil {
+		source.files = r.MultipartForm.File
+	}
+	if err := mapForm(obj, source); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+type formMultipartBinding struct{}
+
+func (formMultipartBinding) Name() string {
+	return "multipart/form-data"
+}
+
+// Bind 只绑定multipart/form-data表单中的参数和文件
+func (formMultipartBinding) Bind(r *http.Request, obj any) error {
+	if err := r.ParseMultipartForm(defaultMemory); err != nil {
+		return err
+	}
+	source := &formSource{values: r.MultipartForm.Value, files: r.MultipartForm.File, tag: "form"}
+	if err := mapForm(obj, source); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+type headerBinding struct{}
+
+func (headerBinding) Name() string {
+	return "header"
+}
+
+func (headerBinding) Bind(r *http.Request, obj any) error {
+	source := &formSource{values: r.Header, tag: "header", lookup: func(values map[string][]string, key string) ([]string, bool) {
+		v, ok := values[http.CanonicalHeaderKey(key)]
+		return v, ok
+	}}
+	if err := mapForm(obj, source); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+type uriBinding struct{}
+
+func (uriBinding) Name() string {
+	return "uri"
+}
+
+// BindUri 绑定路由参数(如/user/:id中的id)
+func (uriBinding) BindUri(params map[string][]string, obj any) error {
+	if err := mapForm(obj, &formSource{values: params, tag: "uri"}); err != nil {
+		return err
+	}
+	return validate(obj)
+}
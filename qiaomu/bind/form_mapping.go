@@ -0,0 +1,368 @@
+package bind
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	durationType   = reflect.TypeOf(time.Duration(0))
+	fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+	textType       = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// 字段取值来源：查询参数、表单、请求头或路由参数，以及multipart表单中的文件
+type formSource struct {
+	values map[string][]string
+	files  map[string][]*multipart.FileHeader
+	tag    string
+	// 请求头的键不区分大小写
+	lookup func(values map[string][]string, key string) ([]string, bool)
+}
+
+func (s *formSource) get(key string) ([]string, bool) {
+	if s.lookup != nil {
+		return s.lookup(s.values, key)
+	}
+	values, ok := s.values[key]
+	return values, ok
+}
+
+// 是否存在以prefix开头的键
+func (s *formSource) hasPrefix(prefix string) bool {
+	match := func(key string) bool {
+		if s.lookup != nil {
+			return len(key) >= len(prefix) && strings.EqualFold(key[:len(prefix)], prefix)
+		}
+		return strings.HasPrefix(key, prefix)
+	}
+	for key := range s.values {
+		if match(key) {
+			return true
+		}
+	}
+	for key := range s.files {
+		if match(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// mapForm 按字段的tag(如form:"name")将values和files映射到结构体obj中
+// 支持的字段类型：整数、浮点数、bool、string、time.Time(time_format:"2006-01-02"，或unix、unixmilli)、time.Duration、
+// 实现了encoding.TextUnmarshaler的类型、以上类型的切片和数组、map[string]T(键为name[key])、
+// *multipart.FileHeader和[]*multipart.FileHeader、嵌套结构体及以上类型的指针
+// tag可以带默认值，如form:"page,default=1"；tag为-时忽略该字段；没有tag时使用字段名；
+// 有tag的嵌套结构体的字段键为 tag.字段键(如address.city)，没有tag的嵌套结构体和匿名结构体的字段直接使用字段键；
+// 嵌套结构体指针只在存在以其前缀开头的键时才分配，自引用的结构体(如链表节点)在键前缀不再增长时停止递归
+func mapForm(obj any, source *formSource) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return errors.New("bind: argument must be a non-nil pointer")
+	}
+	v = v.Elem()
+	if v.Kind() != reflect.Struct {
+		return errors.New(fmt.Sprintf("bind: %s binding only supports struct, got %s", source.tag, v.Type()))
+	}
+	_, err := mapStruct(v, source, "", map[reflect.Type]int{})
+	return err
+}
+
+// 映射结构体的所有字段，返回是否设置了任何字段；mapping为递归栈上正在映射的结构体类型
+func mapStruct(v reflect.Value, source *formSource, prefix string, mapping map[reflect.Type]int) (bool, error) {
+	t := v.Type()
+	mapping[t]++
+	defer func() { mapping[t]-- }()
+	set := false
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag, tagged := field.Tag.Lookup(source.tag)
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		ok, err := mapField(v.Field(i), field, source, prefix, name, tagged, opts, mapping)
+		if err != nil {
+			return set, err
+		}
+		set = set || ok
+	}
+	return set, nil
+}
+
+func mapField(v reflect.Value, field reflect.StructField, source *formSource, prefix, name string, tagged bool, opts string, mapping map[reflect.Type]int) (bool, error) {
+	key := prefix + name
+	t := field.Type
+
+	// 文件
+	if t == fileHeaderType || (t.Kind() == reflect.Slice && t.Elem() == fileHeaderType) {
+		files := source.files[key]
+		if len(files) == 0 {
+			return false, nil
+		}
+		if t == fileHeaderType {
+			v.Set(reflect.ValueOf(files[0]))
+		} else {
+			v.Set(reflect.ValueOf(files))
+		}
+		return true, nil
+	}
+
+	base := t
+	for base.Kind() == reflect.Pointer {
+		base = base.Elem()
+	}
+	// 嵌套结构体(time.Time和TextUnmarshaler作为单个值处理)
+	if base.Kind() == reflect.Struct && (t == base || t.Elem() == base) &&
+		base != timeType && !reflect.PointerTo(base).Implements(textType) {
+		nestedPrefix := prefix
+		if tagged && !field.Anonymous {
+			nestedPrefix = key + "."
+		}
+		if t.Kind() == reflect.Pointer {
+			// 前缀不变时再次进入正在映射的类型会无限递归；前缀增长时递归深度受键的长度限制
+			if mapping[base] > 0 && nestedPrefix == prefix {
+				return false, nil
+			}
+			if nestedPrefix != prefix && !source.hasPrefix(nestedPrefix) {
+				return false, nil
+			}
+		}
+		nested := reflect.New(base).Elem()
+		target := v
+		if t.Kind() != reflect.Pointer {
+			nested = v
+		} else if !v.IsNil() {
+			nested = v.Elem()
+		}
+		ok, err := mapStruct(nested, source, nestedPrefix, mapping)
+		if err != nil || !ok {
+			return ok, err
+		}
+		if t.Kind() == reflect.Pointer && target.IsNil() {
+			ptr := reflect.New(base)
+			ptr.Elem().Set(nested)
+			target.Set(ptr)
+		}
+		return true, nil
+	}
+
+	// map[string]T，键为name[key]
+	if base.Kind() == reflect.Map && base.Key().Kind() == reflect.String {
+		return mapMap(v, field, source, key)
+	}
+
+	values, ok := source.get(key)
+	if !ok || len(values) == 0 {
+		def, found := defaultValue(opts)
+		if !found {
+			return false, nil
+		}
+		values = []string{def}
+	}
+	if err := setValues(v, field, values); err != nil {
+		return false, errors.New(fmt.Sprintf("bind: field [%s] %v", key, err))
+	}
+	return true, nil
+}
+
+func mapMap(v reflect.Value, field reflect.StructField, source *formSource, key string) (bool, error) {
+	t := v.Type()
+	m := reflect.MakeMap(t)
+	for k, values := range source.values {
+		if !strings.HasPrefix(k, key+"[") || !strings.HasSuffix(k, "]") || len(values) == 0 {
+			continue
+		}
+		elem := reflect.New(t.Elem()).Elem()
+		if err := setValues(elem, field, values); err != nil {
+			return false, errors.New(fmt.Sprintf("bind: field [%s] %v", k, err))
+		}
+		m.SetMapIndex(reflect.ValueOf(k[len(key)+1:len(k)-1]), elem)
+	}
+	if m.Len() == 0 {
+		return false, nil
+	}
+	v.Set(m)
+	return true, nil
+}
+
+func defaultValue(opts string) (string, bool) {
+	for _, opt := range strings.Split(opts, ",") {
+		if strings.HasPrefix(opt, "default=") {
+			return strings.TrimPrefix(opt, "default="), true
+		}
+	}
+	return "", false
+}
+
+// 将values设置到v中(切片和数组使用全部值，其他类型使用第一个值)
+func setValues(v reflect.Value, field reflect.StructField, values []string) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValues(v.Elem(), field, values)
+	case reflect.Slice:
+		if !reflect.PointerTo(v.Type()).Implements(textType) {
+			slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+			for i, value := range values {
+				if err := setValue(slice.Index(i), field, value); err != nil {
+					return err
+				}
+			}
+			v.Set(slice)
+			return nil
+		}
+	case reflect.Array:
+		if len(values) != v.Len() {
+			return errors.New(fmt.Sprintf("got %d values, want %d", len(values), v.Len()))
+		}
+		for i, value := range values {
+			if err := setValue(v.Index(i), field, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return setValue(v, field, values[0])
+}
+
+func setValue(v reflect.Value, field reflect.StructField, value string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValue(v.Elem(), field, value)
+	}
+	switch v.Type() {
+	case timeType:
+		return setTime(v, field, value)
+	case durationType:
+		if value == "" {
+			v.SetInt(0)
+			return nil
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		if value == "" {
+			v.SetBool(false)
+			return nil
+		}
+		b, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value == "" {
+			value = "0"
+		}
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value == "" {
+			value = "0"
+		}
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if value == "" {
+			value = "0"
+		}
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Interface:
+		v.Set(reflect.ValueOf(value))
+	default:
+		return errors.New(fmt.Sprintf("unsupported type %s", v.Type()))
+	}
+	return nil
+}
+
+// 除strconv.ParseBool支持的值外，还支持on/off(HTML复选框)和yes/no
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "on", "yes":
+		return true, nil
+	case "off", "no":
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// time.Time字段：time_format为布局(默认RFC3339)或unix、unixmilli，time_location为时区(默认本地时区，time_utc:"1"时为UTC)
+func setTime(v reflect.Value, field reflect.StructField, value string) error {
+	if value == "" {
+		v.Set(reflect.ValueOf(time.Time{}))
+		return nil
+	}
+	format := field.Tag.Get("time_format")
+	switch format {
+	case "unix", "unixmilli":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		t := time.Unix(n, 0)
+		if format == "unixmilli" {
+			t = time.UnixMilli(n)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case "":
+		format = time.RFC3339
+	}
+	loc := time.Local
+	if field.Tag.Get("time_utc") == "1" {
+		loc = time.UTC
+	}
+	if name := field.Tag.Get("time_location"); name != "" {
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return err
+		}
+		loc = l
+	}
+	t, err := time.ParseInLocation(format, value, loc)
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(t))
+	return nil
+}
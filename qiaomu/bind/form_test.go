@@ -0,0 +1,162 @@
+package bind
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type address struct {
+	City string `form:"city"`
+	Zip  *int   `form:"zip"`
+}
+
+type pageQuery struct {
+	Page     int               `form:"page,default=1"`
+	Size     uint8             `form:"size"`
+	Keyword  *string           `form:"keyword"`
+	Tags     []string          `form:"tag"`
+	IDs      [2]int64          `form:"id"`
+	OnSale   bool              `form:"on_sale"`
+	Price    float64           `form:"price"`
+	From     time.Time         `form:"from" time_format:"2006-01-02" time_utc:"1"`
+	Until    time.Time         `form:"until" time_format:"unix"`
+	Timeout  time.Duration     `form:"timeout"`
+	Address  address           `form:"address"`
+	Shipping *address          `form:"shipping"`
+	Extra    map[string]string `form:"extra"`
+	Ignored  string            `form:"-"`
+	Name     string
+}
+
+func TestQueryBinding(t *testing.T) {
+	query := url.Values{
+		"size":         {"20"},
+		"keyword":      {"phone"},
+		"tag":          {"new", "hot"},
+		"id":           {"1", "2"},
+		"on_sale":      {"on"},
+		"price":        {"9.5"},
+		"from":         {"2024-01-02"},
+		"until":        {"1700000000"},
+		"timeout":      {"1m30s"},
+		"address.city": {"beijing"},
+		"address.zip":  {"100000"},
+		"extra[color]": {"red"},
+		"Ignored":      {"x"},
+		"Name":         {"qiaomu"},
+	}
+	r := httptest.NewRequest(http.MethodGet, "/goods?"+query.Encode(), nil)
+	var q pageQuery
+	if err := Query.Bind(r, &q); err != nil {
+		t.Fatal(err)
+	}
+	zip := 100000
+	keyword := "phone"
+	want := pageQuery{
+		Page:    1,
+		Size:    20,
+		Keyword: &keyword,
+		Tags:    []string{"new", "hot"},
+		IDs:     [2]int64{1, 2},
+		OnSale:  true,
+		Price:   9.5,
+		From:    time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		Until:   time.Unix(1700000000, 0),
+		Timeout: 90 * time.Second,
+		Address: address{City: "beijing", Zip: &zip},
+		Extra:   map[string]string{"color": "red"},
+		Name:    "qiaomu",
+	}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("got  %+v\nwant %+v", q, want)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/goods?size=300", nil)
+	if err := Query.Bind(r, &pageQuery{}); err == nil || !strings.Contains(err.Error(), "[size]") {
+		t.Errorf("got %v, want size overflow error", err)
+	}
+}
+
+func TestFormMultipartBinding(t *testing.T) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("title", "phone")
+	for _, name := range []string{"a.png", "b.png"} {
+		fw, _ := mw.CreateFormFile("images", name)
+		fw.Write([]byte(name))
+	}
+	mw.Close()
+	r := httptest.NewRequest(http.MethodPost, "/goods", &buf)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+
+	var form struct {
+		Title  string                  `form:"title" validate:"required"`
+		Cover  *multipart.FileHeader   `form:"images"`
+		Images []*multipart.FileHeader `form:"images"`
+	}
+	if err := FormMultipart.Bind(r, &form); err != nil {
+		t.Fatal(err)
+	}
+	if form.Title != "phone" || form.Cover.Filename != "a.png" || len(form.Images) != 2 {
+		t.Errorf("got %+v", form)
+	}
+}
+
+func TestHeaderBinding(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Request-Id", "abc")
+	r.Header.Set("X-Page", "3")
+	var h struct {
+		RequestID string `header:"x-request-id"`
+		Page      int    `header:"X-Page"`
+	}
+	if err := Header.Bind(r, &h); err != nil {
+		t.Fatal(err)
+	}
+	if h.RequestID != "abc" || h.Page != 3 {
+		t.Errorf("got %+v", h)
+	}
+}
+
+func TestDefaultBinding(t *testing.T) {
+	tests := []struct {
+		method, contentType string
+		want                Binding
+	}{
+		{http.MethodGet, "application/json", Form},
+		{http.MethodPost, "application/json; charset=utf-8", JSON},
+		{http.MethodPut, "text/xml", XML},
+		{http.MethodPost, "multipart/form-data; boundary=x", FormMultipart},
+		{http.MethodPost, "application/x-www-form-urlencoded", Form},
+	}
+	for _, tt := range tests {
+		if got := Default(tt.method, tt.contentType); got != tt.want {
+			t.Errorf("Default(%s, %s) = %s, want %s", tt.method, tt.contentType, got.Name(), tt.want.Name())
+		}
+	}
+}
+
+type node struct {
+	Name string `form:"name"`
+	Next *node
+	Link *node `form:"link"`
+}
+
+func TestQueryBindingSelfReferential(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?name=a&link.name=b&link.link.name=c", nil)
+	var n node
+	if err := Query.Bind(r, &n); err != nil {
+		t.Fatal(err)
+	}
+	if n.Name != "a" || n.Next != nil || n.Link == nil || n.Link.Name != "b" ||
+		n.Link.Link == nil || n.Link.Link.Name != "c" || n.Link.Link.Link != nil {
+		t.Fatalf("unexpected node %+v", n)
+	}
+}
@@ -6,11 +6,20 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"strings"
+	"sync"
 )
 
+// RequiredTag 标记必填字段的tag，如 Sku string `json:"sku" queen:"required"`
+// 必填字段在JSON中必须存在且不为null，嵌套结构体、结构体指针、结构体切片和map中的字段同样会被检查
+const RequiredTag = "queen"
+
+// Deprecated: 旧版本中结构体字段使用的必填tag(msgo:"required")，仍然有效，新代码请使用RequiredTag
+const DeprecatedRequiredTag = "msgo"
+
 type jsonBinding struct {
 	DisallowUnknownFields bool
-	IsValidate            bool
+	IsValidate            bool // 检查必填字段(见RequiredTag)
 }
 
 func (jsonBinding) Name() string {
@@ -28,84 +37,422 @@ func (b jsonBinding) Bind(r *http.Request, obj any) error {
 		decoder.DisallowUnknownFields()
 	}
 	if b.IsValidate {
-		err := validateParam(obj, decoder)
-		if err != nil {
-			return err
-		}
-	} else {
-		err := decoder.Decode(obj)
-		if err != nil {
+		// 解码的同时记录缺少的必填字段
+		if err := decodeRequired(decoder, obj, b.DisallowUnknownFields); err != nil {
 			return err
 		}
+	} else if err := decoder.Decode(obj); err != nil {
+		return err
 	}
 	return validate(obj)
 }
 
-func validateParam(obj any, decoder *json.Decoder) error {
-	valueOf := reflect.ValueOf(obj) // 反射，获取obj的类型
-	// 判断其是否为指针类型
-	if valueOf.Kind() != reflect.Pointer {
-		return errors.New("This argument must have a pointer type")
+// MissingFieldsError 缺少必填字段，Paths为所有缺少字段的完整JSON路径(如items[2].sku)
+type MissingFieldsError struct {
+	Paths []string
+}
+
+func (e *MissingFieldsError) Error() string {
+	return fmt.Sprintf("required fields are missing: %s", strings.Join(e.Paths, ", "))
+}
+
+// 按obj的类型逐个token解码JSON，解码的同时收集缺少的必填字段
+// 不包含必填字段的值直接交给decoder.Decode，整个请求体只解析一遍
+func decodeRequired(decoder *json.Decoder, obj any, disallowUnknown bool) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Pointer || v.IsNil() || !needsCheck(v.Type()) {
+		return decoder.Decode(obj)
+	}
+	d := &requiredDecoder{decoder: decoder, disallowUnknown: disallowUnknown}
+	if _, err := d.value(v.Elem(), ""); err != nil {
+		return err
+	}
+	if len(d.missing) > 0 {
+		return &MissingFieldsError{Paths: d.missing}
+	}
+	return nil
+}
+
+type requiredDecoder struct {
+	decoder         *json.Decoder
+	disallowUnknown bool
+	missing         []string
+}
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+var needsCheckCache sync.Map // map[reflect.Type]bool
+
+// 类型中(包括嵌套的结构体、切片和map)是否有必填字段
+func needsCheck(t reflect.Type) bool {
+	if ok, loaded := needsCheckCache.Load(t); loaded {
+		return ok.(bool)
 	}
-	elem := valueOf.Elem().Interface()
-	of := reflect.ValueOf(elem)
+	ok := hasRequired(t, make(map[reflect.Type]bool))
+	needsCheckCache.Store(t, ok)
+	return ok
+}
 
-	switch of.Kind() {
+func hasRequired(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// 自定义了解码方法的类型不检查内部字段
+	if visiting[t] || reflect.PointerTo(t).Implements(unmarshalerType) || reflect.PointerTo(t).Implements(textType) {
+		return false
+	}
+	visiting[t] = true
+	switch t.Kind() {
 	case reflect.Struct:
-		return checkParam(of, obj, decoder)
+		for _, field := range jsonFields(t) {
+			if field.required || hasRequired(field.typ, visiting) {
+				return true
+			}
+		}
 	case reflect.Slice, reflect.Array:
-		elem := of.Type().Elem()
-		if elem.Kind() == reflect.Struct {
-			return checkParamSlice(elem, obj, decoder)
+		return hasRequired(t.Elem(), visiting)
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && hasRequired(t.Elem(), visiting)
+	}
+	return false
+}
+
+// 解码一个JSON值到v，返回该值是否为null
+func (d *requiredDecoder) value(v reflect.Value, path string) (bool, error) {
+	if !needsCheck(v.Type()) {
+		return d.leaf(v)
+	}
+	token, err := d.decoder.Token()
+	if err != nil {
+		return false, err
+	}
+	if token == nil {
+		setNull(v)
+		return true, nil
+	}
+	return false, d.composite(v, token, path)
+}
+
+// 解码到指向v副本的指针，值为null时指针被置为nil，以此区分null
+func (d *requiredDecoder) leaf(v reflect.Value) (bool, error) {
+	elem := reflect.New(v.Type())
+	elem.Elem().Set(v)
+	ptr := reflect.New(elem.Type())
+	ptr.Elem().Set(elem)
+	if err := d.decoder.Decode(ptr.Interface()); err != nil {
+		return false, err
+	}
+	if ptr.Elem().IsNil() {
+		setNull(v)
+		return true, nil
+	}
+	v.Set(elem.Elem())
+	return v.Kind() == reflect.Pointer && v.IsNil(), nil
+}
+
+// 带有,string选项的字段，值为包含JSON字面量的字符串
+func (d *requiredDecoder) quoted(v reflect.Value) (bool, error) {
+	token, err := d.decoder.Token()
+	if err != nil {
+		return false, err
+	}
+	if token == nil {
+		setNull(v)
+		return true, nil
+	}
+	s, ok := token.(string)
+	if !ok {
+		return false, d.typeError(token, v.Type(), "")
+	}
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if err := json.Unmarshal([]byte(s), v.Addr().Interface()); err != nil {
+		return false, errors.New(fmt.Sprintf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", s, v.Type()))
+	}
+	return false, nil
+}
+
+// 与encoding/json相同：null将指针、切片、map和接口置为nil，其他类型保持不变
+func setNull(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+		v.Set(reflect.Zero(v.Type()))
+	}
+}
+
+func (d *requiredDecoder) composite(v reflect.Value, token json.Token, path string) error {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	delim, _ := token.(json.Delim)
+	switch {
+	case delim == '{' && v.Kind() == reflect.Struct:
+		return d.object(v, path)
+	case delim == '{' && v.Kind() == reflect.Map:
+		return d.mapValues(v, path)
+	case delim == '[' && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array):
+		return d.array(v, path)
+	}
+	return d.typeError(token, v.Type(), path)
+}
+
+func (d *requiredDecoder) object(v reflect.Value, path string) error {
+	fields := jsonFields(v.Type())
+	present := make(map[string]bool, len(fields))
+	for d.decoder.More() {
+		token, err := d.decoder.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := token.(string)
+		field := matchField(fields, key)
+		if field == nil {
+			if d.disallowUnknown {
+				return errors.New(fmt.Sprintf("json: unknown field %q", key))
+			}
+			if err := d.skipValue(); err != nil {
+				return err
+			}
+			continue
+		}
+		fv := fieldByIndex(v, field.index)
+		var isNull bool
+		if field.quoted {
+			isNull, err = d.quoted(fv)
+		} else {
+			isNull, err = d.value(fv, joinPath(path, field.name))
+		}
+		if err != nil {
+			return err
+		}
+		present[field.name] = present[field.name] || !isNull
+	}
+	// 结束的}
+	if _, err := d.decoder.Token(); err != nil {
+		return err
+	}
+	for _, field := range fields {
+		if field.required && !present[field.name] {
+			d.missing = append(d.missing, joinPath(path, field.name))
 		}
-	default:
-		_ = decoder.Decode(obj)
 	}
 	return nil
 }
 
-func checkParamSlice(of reflect.Type, obj any, decoder *json.Decoder) error {
-	mapValue := make([]map[string]interface{}, 0)
-	_ = decoder.Decode(&mapValue)
-	for i := 0; i < of.NumField(); i++ {
-		field := of.Field(i)
-		name := field.Name
-		jsonName := field.Tag.Get("json")
-		if jsonName != "" {
-			name = jsonName
-		}
-		required := field.Tag.Get("queen")
-		for _, v := range mapValue {
-			value := v[name]
-			if value == nil && required == "required" {
-				return errors.New(fmt.Sprintf("filed [%s] is not exist,because [%s] is required", jsonName, jsonName))
+func (d *requiredDecoder) array(v reflect.Value, path string) error {
+	i := 0
+	if v.Kind() == reflect.Slice {
+		v.SetLen(0)
+	}
+	for ; d.decoder.More(); i++ {
+		if v.Kind() == reflect.Slice {
+			v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+		} else if i >= v.Len() {
+			// 超出数组长度的元素被丢弃
+			if err := d.skipValue(); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := d.value(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+	if v.Kind() == reflect.Array {
+		for ; i < v.Len(); i++ {
+			v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+		}
+	} else if v.IsNil() {
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	}
+	_, err := d.decoder.Token()
+	return err
+}
+
+func (d *requiredDecoder) mapValues(v reflect.Value, path string) error {
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+	for d.decoder.More() {
+		token, err := d.decoder.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := token.(string)
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if _, err := d.value(elem, joinPath(path, key)); err != nil {
+			return err
+		}
+		v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+	}
+	_, err := d.decoder.Token()
+	return err
+}
+
+// 跳过一个不需要解码的值
+func (d *requiredDecoder) skipValue() error {
+	token, err := d.decoder.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || (delim != '{' && delim != '[') {
+		return nil
+	}
+	for depth := 1; depth > 0; {
+		token, err := d.decoder.Token()
+		if err != nil {
+			return err
+		}
+		if delim, ok := token.(json.Delim); ok {
+			if delim == '{' || delim == '[' {
+				depth++
+			} else {
+				depth--
 			}
 		}
 	}
-	b, _ := json.Marshal(mapValue)
-	_ = json.Unmarshal(b, obj)
 	return nil
 }
 
-func checkParam(of reflect.Value, obj any, decoder *json.Decoder) error {
-	// 解析为map，然后根据map中的key 进行比对
-	// 判断类型 结构体 才能解析为map
-	mapValue := make(map[string]interface{})
-	_ = decoder.Decode(&mapValue)
-	for i := 0; i < of.NumField(); i++ {
-		field := of.Type().Field(i)
-		name := field.Name
-		jsonName := field.Tag.Get("json")
-		if jsonName != "" {
-			name = jsonName
+func (d *requiredDecoder) typeError(token json.Token, t reflect.Type, path string) error {
+	var value string
+	switch token.(type) {
+	case json.Delim:
+		value = "object"
+		if token == json.Delim('[') {
+			value = "array"
+		}
+	case string:
+		value = "string"
+	case bool:
+		value = "bool"
+	default:
+		value = "number"
+	}
+	return &json.UnmarshalTypeError{Value: value, Type: t, Offset: d.decoder.InputOffset(), Field: path}
+}
+
+// 与reflect.Value.FieldByIndex相同，但会为nil的匿名结构体指针分配内存
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+type jsonField struct {
+	name     string
+	index    []int // 字段在结构体中的位置(匿名结构体中的字段有多级)
+	typ      reflect.Type
+	required bool
+	quoted   bool // 带有,string选项的基本类型字段
+}
+
+var jsonFieldCache sync.Map // map[reflect.Type][]jsonField
+
+// 结构体中参与JSON解码的字段(包括匿名结构体中被提升的字段)
+func jsonFields(t reflect.Type) []jsonField {
+	if fields, ok := jsonFieldCache.Load(t); ok {
+		return fields.([]jsonField)
+	}
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				// 未导出的匿名结构体指针无法分配，encoding/json同样不会解码其中的字段
+				if !field.IsExported() {
+					continue
+				}
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for _, f := range jsonFields(ft) {
+					f.index = append([]int{i}, f.index...)
+					fields = append(fields, f)
+				}
+				continue
+			}
 		}
-		required := field.Tag.Get("msgo")
-		value := mapValue[name]
-		if value == nil && required == "required" {
-			return errors.New(fmt.Sprintf("filed [%s] is not exist,because [%s] is required", jsonName, jsonName))
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, jsonField{
+			name:     name,
+			index:    []int{i},
+			typ:      field.Type,
+			required: hasOption(field.Tag.Get(RequiredTag), "required") || hasOption(field.Tag.Get(DeprecatedRequiredTag), "required"),
+			quoted:   hasOption(opts, "string") && isQuotable(field.Type),
+		})
+	}
+	jsonFieldCache.Store(t, fields)
+	return fields
+}
+
+// 与encoding/json相同：优先精确匹配，其次不区分大小写匹配
+func matchField(fields []jsonField, key string) *jsonField {
+	for i := range fields {
+		if fields[i].name == key {
+			return &fields[i]
+		}
+	}
+	for i := range fields {
+		if strings.EqualFold(fields[i].name, key) {
+			return &fields[i]
 		}
 	}
-	b, _ := json.Marshal(mapValue)
-	_ = json.Unmarshal(b, obj)
 	return nil
 }
+
+// encoding/json只对基本类型应用,string选项
+func isQuotable(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func hasOption(tag, option string) bool {
+	for _, opt := range strings.Split(tag, ",") {
+		if strings.TrimSpace(opt) == option {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package bind
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type orderItem struct {
+	Sku   string `json:"sku" queen:"required"`
+	Count int    `json:"count"`
+}
+
+type base struct {
+	UserID int64 `json:"user_id" queen:"required"`
+}
+
+type order struct {
+	base
+	No       string               `json:"no" queen:"required"`
+	Address  *orderAddress        `json:"address"`
+	Items    []orderItem          `json:"items" queen:"required"`
+	Gifts    map[string]orderItem `json:"gifts"`
+	Remark   string               `json:"remark"`
+	Internal string               `json:"-" queen:"required"`
+}
+
+type orderAddress struct {
+	City string `json:"city" queen:"required"`
+}
+
+func bindJSON(b Binding, body string, obj any) error {
+	r := httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(body))
+	return b.Bind(r, obj)
+}
+
+func TestJSONRequired(t *testing.T) {
+	b := jsonBinding{IsValidate: true}
+	var o order
+	err := bindJSON(b, `{"user_id":1,"no":"A1","address":{"city":"beijing"},
+		"items":[{"sku":"s1","count":1},{"sku":"s2"}],"gifts":{"g1":{"sku":"s3"}}}`, &o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.UserID != 1 || o.Address.City != "beijing" || len(o.Items) != 2 || o.Gifts["g1"].Sku != "s3" {
+		t.Errorf("got %+v", o)
+	}
+
+	err = bindJSON(b, `{"No":"A1","address":{},"items":[{"sku":"s1"},{"count":2},{"sku":null}],
+		"gifts":{"g1":{"count":1}},"extra":{"nested":[1,{"sku":1}]}}`, &order{})
+	var missing *MissingFieldsError
+	if !errors.As(err, &missing) {
+		t.Fatalf("got %v, want MissingFieldsError", err)
+	}
+	want := []string{"address.city", "items[1].sku", "items[2].sku", "gifts.g1.sku", "user_id"}
+	if !reflect.DeepEqual(missing.Paths, want) {
+		t.Errorf("got %v, want %v", missing.Paths, want)
+	}
+	if err.Error() != "required fields are missing: address.city, items[1].sku, items[2].sku, gifts.g1.sku, user_id" {
+		t.Errorf("got %q", err.Error())
+	}
+
+	// 顶层为切片
+	var items []orderItem
+	err = bindJSON(b, `[{"sku":"a"},{"count":1}]`, &items)
+	if !errors.As(err, &missing) || !reflect.DeepEqual(missing.Paths, []string{"[1].sku"}) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestJSONBindingErrors(t *testing.T) {
+	// 解码错误不再被忽略
+	if err := bindJSON(jsonBinding{IsValidate: true}, `{"items":"x"}`, &order{}); err == nil {
+		t.Error("want type error")
+	}
+	if err := bindJSON(jsonBinding{DisallowUnknownFields: true}, `{"sku":"a","color":"red"}`, &orderItem{}); err == nil {
+		t.Error("want unknown field error")
+	}
+	// 不检查必填字段时缺少字段不报错
+	if err := bindJSON(JSON, `{}`, &orderItem{}); err != nil {
+		t.Error(err)
+	}
+	// 旧的msgo:"required"仍然有效
+	var legacy struct {
+		Name string `json:"name" msgo:"required"`
+	}
+	var missing *MissingFieldsError
+	if err := bindJSON(jsonBinding{IsValidate: true}, `{}`, &legacy); !errors.As(err, &missing) || missing.Paths[0] != "name" {
+		t.Errorf("got %v, want missing name", err)
+	}
+	// ,string选项、数组和匿名结构体指针与encoding/json的解码结果一致
+	type Address = orderAddress
+	type quoted struct {
+		*Address
+		ID    int64        `json:"id,string" queen:"required"`
+		Pairs [2]orderItem `json:"pairs"`
+	}
+	var q quoted
+	if err := bindJSON(jsonBinding{IsValidate: true}, `{"id":"42","city":"sh","pairs":[{"sku":"a"},{"sku":"b"},{"sku":"c"}]}`, &q); err != nil {
+		t.Fatal(err)
+	}
+	if q.ID != 42 || q.Address == nil || q.City != "sh" || q.Pairs[1].Sku != "b" {
+		t.Errorf("got %+v", q)
+	}
+	if err := bindJSON(jsonBinding{IsValidate: true}, `{"id":null,"city":"sh"}`, &quoted{}); !errors.As(err, &missing) || missing.Paths[0] != "id" {
+		t.Errorf("got %v, want missing id", err)
+	}
+	if err := bindJSON(jsonBinding{IsValidate: true, DisallowUnknownFields: true}, `{"sku":"a","color":"red"}`, &orderItem{}); err == nil {
+		t.Error("want unknown field error")
+	}
+}
@@ -0,0 +1,92 @@
+package qiaomu
+
+import (
+	"errors"
+	"io"
+	"net/http"
+)
+
+// ErrBodyTooLarge 请求体超过MaxBodyBytes限制时读取请求体返回的错误
+var ErrBodyTooLarge = errors.New("qiaomu: request body too large")
+
+// MaxBodyBytes 限制请求体大小的中间件(按路由或路由组使用，覆盖Engine.MaxBodyBytes，n<=0时不限制)
+// 读取请求体(绑定参数、解析表单等)超过限制时返回ErrBodyTooLarge，并以413响应请求
+func MaxBodyBytes(n int64) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			ctx.maxBodyBytes = n
+			ctx.limitBody()
+			next(ctx)
+		}
+	}
+}
+
+// 为请求体加上大小限制(限制值在读取时从ctx.maxBodyBytes获取，路由中间件可以覆盖全局限制)
+func (c *Context) limitBody() {
+	if c.R == nil || c.R.Body == nil || c.R.Body == http.NoBody {
+		return
+	}
+	if _, ok := c.R.Body.(*maxBytesReader); ok {
+		return
+	}
+	c.R.Body = &maxBytesReader{ctx: c, rc: c.R.Body}
+}
+
+// BodyTooLarge 读取请求体时是否超过了MaxBodyBytes限制
+func (c *Context) BodyTooLarge() bool {
+	return c.bodyTooLarge
+}
+
+// 请求体超过限制且处理函数还未响应时返回413
+func (c *Context) checkBodyTooLarge() {
+	if c.bodyTooLarge && !c.W.Written() {
+		c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+	}
+}
+
+type maxBytesReader struct {
+	ctx          *Context
+	rc           io.ReadCloser
+	read         int64
+	err          error
+	defaultLimit int64 // ctx.maxBodyBytes<=0时使用的限制(0表示不限制)
+}
+
+func (r *maxBytesReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	limit := r.ctx.maxBodyBytes
+	if limit <= 0 {
+		limit = r.defaultLimit
+	}
+	if limit <= 0 {
+		return r.rc.Read(p)
+	}
+	// Content-Length已经超过限制时不再读取
+	if r.ctx.R.ContentLength > limit {
+		return 0, r.tooLarge()
+	}
+	// 多读一个字节用于判断是否超过限制
+	if remaining := limit - r.read + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := r.rc.Read(p)
+	r.read += int64(n)
+	if r.read > limit {
+		return n - int(r.read-limit), r.tooLarge()
+	}
+	return n, err
+}
+
+func (r *maxBytesReader) tooLarge() error {
+	r.err = ErrBodyTooLarge
+	r.ctx.bodyTooLarge = true
+	// 请求体没有读完，响应后关闭连接
+	r.ctx.W.Header().Set("Connection", "close")
+	return r.err
+}
+
+func (r *maxBytesReader) Close() error {
+	return r.rc.Close()
+}
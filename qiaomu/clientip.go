@@ -0,0 +1,209 @@
+package qiaomu
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// SetTrustedProxies 设置可信代理(负载均衡器、网关等)的IP或CIDR，如[]string{"10.0.0.0/8", "192.168.1.10"}
+// 只有直接连接的对端是可信代理时，ClientIP、Scheme和Host才会使用X-Forwarded-For、X-Real-IP、Forwarded、X-Forwarded-Proto等请求头；
+// 默认不信任任何代理
+func (e *Engine) SetTrustedProxies(proxies []string) error {
+	cidrs := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return errors.New(fmt.Sprintf("trusted proxy [%s] is not a valid IP or CIDR", proxy))
+			}
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			proxy = fmt.Sprintf("%s/%d", proxy, bits)
+		}
+		_, cidr, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return errors.New(fmt.Sprintf("trusted proxy [%s] is not a valid IP or CIDR", proxy))
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	e.trustedCIDRs = cidrs
+	return nil
+}
+
+func (e *Engine) isTrustedProxy(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range e.trustedCIDRs {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoteIP 直接连接的对端IP(R.RemoteAddr去掉端口)
+func (c *Context) RemoteIP() string {
+	ip, _, err := net.SplitHostPort(strings.TrimSpace(c.R.RemoteAddr))
+	if err != nil {
+		return strings.TrimSpace(c.R.RemoteAddr)
+	}
+	return ip
+}
+
+// 对端是否是可信代理
+func (c *Context) fromTrustedProxy() bool {
+	return c.engine != nil && c.engine.isTrustedProxy(net.ParseIP(c.RemoteIP()))
+}
+
+// ClientIP 客户端IP：对端是可信代理时依次使用Forwarded、X-Forwarded-For、X-Real-IP，
+// 其中代理链从右向左跳过可信代理，第一个不可信的地址即为客户端IP；否则为对端IP
+func (c *Context) ClientIP() string {
+	remoteIP := c.RemoteIP()
+	if !c.fromTrustedProxy() {
+		return remoteIP
+	}
+	var chain []string
+	for _, element := range parseForwarded(c.R.Header.Values("Forwarded")) {
+		if element["for"] != "" {
+			chain = append(chain, element["for"])
+		}
+	}
+	if len(chain) == 0 {
+		for _, value := range c.R.Header.Values("X-Forwarded-For") {
+			chain = append(chain, strings.Split(value, ",")...)
+		}
+	}
+	if ip := c.engine.resolveChain(chain); ip != "" {
+		return ip
+	}
+	if ip := net.ParseIP(strings.TrimSpace(c.R.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	return remoteIP
+}
+
+// 从右向左跳过可信代理，返回第一个不可信的地址(全部可信时返回最左边的地址)
+func (e *Engine) resolveChain(chain []string) string {
+	var client string
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := parseForwardedIP(chain[i])
+		if ip == nil {
+			// 无法解析的地址(如Forwarded中的unknown、混淆标识)视为不可信，之前解析到的地址即为客户端IP
+			break
+		}
+		client = ip.String()
+		if !e.isTrustedProxy(ip) {
+			break
+		}
+	}
+	return client
+}
+
+// Scheme 请求的协议(http或https)：对端是可信代理时使用Forwarded的proto或X-Forwarded-Proto，
+// 与ClientIP相同从右向左跳过可信代理，使用最外层可信代理添加的值(更左边的值可能由客户端伪造)
+func (c *Context) Scheme() string {
+	if c.fromTrustedProxy() {
+		if proto := c.forwardedParam("proto"); proto != "" {
+			return strings.ToLower(proto)
+		}
+		if proto := c.forwardedValue("X-Forwarded-Proto"); proto != "" {
+			return strings.ToLower(proto)
+		}
+	}
+	if c.R.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// Host 请求的Host(可能带端口)：对端是可信代理时使用Forwarded的host或X-Forwarded-Host，选取方式同Scheme
+func (c *Context) Host() string {
+	if c.fromTrustedProxy() {
+		if host := c.forwardedParam("host"); host != "" {
+			return host
+		}
+		if host := c.forwardedValue("X-Forwarded-Host"); host != "" {
+			return host
+		}
+	}
+	return c.R.Host
+}
+
+// Forwarded中最外层可信代理添加的参数：从右向左遍历，遇到for不是可信代理的元素(该元素由最外层可信代理添加)时停止
+func (c *Context) forwardedParam(key string) string {
+	elements := parseForwarded(c.R.Header.Values("Forwarded"))
+	var value string
+	for i := len(elements) - 1; i >= 0; i-- {
+		if v := elements[i][key]; v != "" {
+			value = v
+		}
+		if !c.engine.isTrustedProxy(parseForwardedIP(elements[i]["for"])) {
+			break
+		}
+	}
+	return value
+}
+
+// 每经过一个代理追加一个值的请求头(如X-Forwarded-Proto)中最外层可信代理添加的值：
+// 对端和X-Forwarded-For中从右向左连续的可信地址各对应一个可信代理，值不足时使用最左边的值
+func (c *Context) forwardedValue(name string) string {
+	var values []string
+	for _, value := range c.R.Header.Values(name) {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	hops := 1
+	var chain []string
+	for _, value := range c.R.Header.Values("X-Forwarded-For") {
+		chain = append(chain, strings.Split(value, ",")...)
+	}
+	for i := len(chain) - 1; i >= 0 && c.engine.isTrustedProxy(parseForwardedIP(chain[i])); i-- {
+		hops++
+	}
+	i := len(values) - hops
+	if i < 0 {
+		i = 0
+	}
+	return values[i]
+}
+
+// 解析Forwarded请求头(RFC 7239)，如 for=192.0.2.60;proto=https, for="[2001:db8::17]:4711"
+func parseForwarded(values []string) []map[string]string {
+	var elements []map[string]string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			pairs := make(map[string]string)
+			for _, pair := range strings.Split(element, ";") {
+				key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+				pairs[strings.ToLower(strings.TrimSpace(key))] = strings.Trim(strings.TrimSpace(val), `"`)
+			}
+			if len(pairs) > 0 {
+				elements = append(elements, pairs)
+			}
+		}
+	}
+	return elements
+}
+
+// 解析代理链中的地址(可能带端口，IPv6可能带方括号)
+func parseForwardedIP(value string) net.IP {
+	value = strings.TrimSpace(value)
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	return net.ParseIP(strings.Trim(value, "[]"))
+}
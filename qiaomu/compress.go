@@ -0,0 +1,426 @@
+package qiaomu
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const defaultCompressMinLength = 1024
+
+// 没有设置MaxBodyBytes时解压后请求体的最大字节数，防止解压炸弹
+const defaultDecompressMaxBytes = 32 << 20
+
+// 默认允许压缩的Content-Type(按前缀匹配)，图片、视频、压缩包等已经压缩过的类型不在其中
+var defaultCompressContentTypes = []string{
+	"text/html", "text/plain", "text/css", "text/javascript", "text/xml", "text/csv",
+	"application/json", "application/javascript", "application/xml", "application/x-javascript",
+	"application/wasm", "image/svg+xml",
+}
+
+// CompressWriter 压缩writer，Reset后可以复用(gzip.Writer、zlib.Writer均满足)
+type CompressWriter interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Compressor 响应压缩算法，可以通过CompressConfig.Compressors接入brotli等第三方实现
+type Compressor interface {
+	Encoding() string                     // Accept-Encoding和Content-Encoding中的名称(如gzip)
+	NewWriter(w io.Writer) CompressWriter // 创建压缩writer(会被池化复用)
+}
+
+// GzipCompressor gzip压缩
+type GzipCompressor struct {
+	Level int // 压缩级别，0表示gzip.DefaultCompression
+}
+
+func (c GzipCompressor) Encoding() string {
+	return "gzip"
+}
+
+func (c GzipCompressor) NewWriter(w io.Writer) CompressWriter {
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	zw, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		zw = gzip.NewWriter(w)
+	}
+	return zw
+}
+
+// DeflateCompressor deflate压缩(HTTP中的deflate为zlib格式，见RFC 9110 8.4.1.2)
+type DeflateCompressor struct {
+	Level int // 压缩级别，0表示zlib.DefaultCompression
+}
+
+func (c DeflateCompressor) Encoding() string {
+	return "deflate"
+}
+
+func (c DeflateCompressor) NewWriter(w io.Writer) CompressWriter {
+	level := c.Level
+	if level == 0 {
+		level = zlib.DefaultCompression
+	}
+	zw, err := zlib.NewWriterLevel(w, level)
+	if err != nil {
+		zw = zlib.NewWriter(w)
+	}
+	return zw
+}
+
+// CompressConfig 响应压缩配置
+type CompressConfig struct {
+	Compressors  []Compressor // 支持的压缩算法，客户端优先级(q值)相同时靠前的优先，默认为gzip、deflate
+	MinLength    int          // 响应体达到该大小才压缩，默认1024字节
+	ContentTypes []string     // 允许压缩的Content-Type(按前缀匹配)，默认为文本、JSON、JS、XML、SVG等
+}
+
+// Compress 响应压缩中间件(默认配置)
+func Compress(next HandlerFunc) HandlerFunc {
+	return CompressWithConfig(CompressConfig{}, next)
+}
+
+// CompressWithConfig 响应压缩中间件：根据请求头Accept-Encoding选择压缩算法，Range请求、HEAD请求和连接升级请求不压缩，
+// 已经设置了Content-Encoding的响应、不在ContentTypes中的响应以及小于MinLength的响应也不压缩
+func CompressWithConfig(conf CompressConfig, next HandlerFunc) HandlerFunc {
+	if len(conf.Compressors) == 0 {
+		conf.Compressors = []Compressor{GzipCompressor{}, DeflateCompressor{}}
+	}
+	if conf.MinLength <= 0 {
+		conf.MinLength = defaultCompressMinLength
+	}
+	if len(conf.ContentTypes) == 0 {
+		conf.ContentTypes = defaultCompressContentTypes
+	}
+	pools := make(map[string]*sync.Pool, len(conf.Compressors))
+	for _, compressor := range conf.Compressors {
+		compressor := compressor
+		pools[compressor.Encoding()] = &sync.Pool{New: func() any {
+			return compressor.NewWriter(io.Discard)
+		}}
+	}
+	return func(ctx *Context) {
+		r := ctx.R
+		if r.Method == http.MethodHead || r.Header.Get("Range") != "" || r.Header.Get("Upgrade") != "" {
+			next(ctx)
+			return
+		}
+		// 客户端不支持压缩时compressor为nil，可压缩的响应同样需要Vary，避免缓存把未压缩的响应返回给支持压缩的客户端
+		w := &compressWriter{ResponseWriter: ctx.W, conf: &conf, status: http.StatusOK}
+		if compressor := negotiateEncoding(r.Header.Get("Accept-Encoding"), conf.Compressors); compressor != nil {
+			w.compressor = compressor
+			w.pool = pools[compressor.Encoding()]
+		}
+		ctx.W = w
+		defer func() {
+			ctx.W = w.ResponseWriter
+			w.finish()
+		}()
+		next(ctx)
+	}
+}
+
+// 根据Accept-Encoding选出q值最高的压缩算法(q值相同时按服务端顺序)，没有可用的返回nil
+func negotiateEncoding(acceptEncoding string, compressors []Compressor) Compressor {
+	if acceptEncoding == "" {
+		return nil
+	}
+	type accepted struct {
+		index int
+		q     float64
+	}
+	qualities := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, q := parseQuality(part)
+		if name == "" {
+			continue
+		}
+		if name == "*" {
+			wildcard = q
+			continue
+		}
+		qualities[name] = q
+	}
+	var candidates []accepted
+	for i, compressor := range compressors {
+		q, ok := qualities[compressor.Encoding()]
+		if !ok {
+			q = wildcard
+		}
+		if q > 0 {
+			candidates = append(candidates, accepted{index: i, q: q})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+	return compressors[candidates[0].index]
+}
+
+// 解析 gzip;q=0.8 这样的值
+func parseQuality(part string) (string, float64) {
+	name, params, _ := strings.Cut(part, ";")
+	name = strings.ToLower(strings.TrimSpace(name))
+	q := 1.0
+	for _, param := range strings.Split(params, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if ok && strings.TrimSpace(key) == "q" {
+			if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = v
+			}
+		}
+	}
+	return name, q
+}
+
+// 延迟到响应体达到MinLength(或者flush)时才决定是否压缩的ResponseWriter
+type compressWriter struct {
+	ResponseWriter
+	conf       *CompressConfig
+	compressor Compressor // 为nil时只负责设置Vary
+	pool       *sync.Pool
+	cw         CompressWriter // 开始压缩后不为nil
+	buf        []byte
+	status     int
+	wroteCode  bool // 处理函数是否调用过WriteHeader
+	decided    bool // 是否已经决定了压缩与否(决定后响应头已经发送)
+	size       int
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.decided || w.wroteCode || code <= 0 {
+		return
+	}
+	// 1xx信息性响应直接发送
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+	w.wroteCode = true
+	// 没有响应体的状态码不需要压缩
+	if code == http.StatusNoContent || code == http.StatusNotModified || code == http.StatusSwitchingProtocols {
+		w.decide(false)
+	}
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, data...)
+		if len(w.buf) < w.conf.MinLength {
+			return len(data), nil
+		}
+		w.decide(w.shouldCompress())
+		return len(data), w.writeBuffered()
+	}
+	return w.write(data)
+}
+
+func (w *compressWriter) write(data []byte) (int, error) {
+	if w.cw != nil {
+		n, err := w.cw.Write(data)
+		w.size += n
+		return n, err
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.size += n
+	return n, err
+}
+
+func (w *compressWriter) writeBuffered() error {
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.write(buf)
+	return err
+}
+
+// 响应是否可以压缩(根据Content-Type和Content-Encoding)，可以压缩时设置Vary
+func (w *compressWriter) compressible() bool {
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(w.buf)
+		header.Set("Content-Type", contentType)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range w.conf.ContentTypes {
+		if strings.HasPrefix(mediaType, allowed) {
+			addVary(header, "Accept-Encoding")
+			return true
+		}
+	}
+	return false
+}
+
+func (w *compressWriter) shouldCompress() bool {
+	return w.compressible() && w.compressor != nil && w.status != http.StatusNoContent && w.status != http.StatusNotModified
+}
+
+// 决定是否压缩并发送响应头
+func (w *compressWriter) decide(compress bool) {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	if compress {
+		header := w.Header()
+		header.Set("Content-Encoding", w.compressor.Encoding())
+		header.Del("Content-Length")
+		header.Del("Accept-Ranges")
+		// 压缩后的内容与原内容字节不同，强ETag改为弱ETag
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		w.cw = w.pool.Get().(CompressWriter)
+		w.cw.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// 处理函数返回后调用：发送未达到MinLength的缓冲数据或者结束压缩
+func (w *compressWriter) finish() {
+	if !w.decided {
+		if !w.wroteCode && len(w.buf) == 0 {
+			// 处理函数没有写任何响应，交给net/http默认处理
+			w.decided = true
+			return
+		}
+		if len(w.buf) > 0 {
+			w.compressible()
+		}
+		w.decide(false)
+		w.writeBuffered()
+	}
+	if w.cw != nil {
+		w.cw.Close()
+		w.cw.Reset(io.Discard)
+		w.pool.Put(w.cw)
+		w.cw = nil
+	}
+}
+
+// Flush 流式响应(如SSE)flush时立即决定是否压缩，不再等待MinLength
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(w.shouldCompress())
+		w.writeBuffered()
+	}
+	if w.cw != nil {
+		w.cw.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return w.ResponseWriter.Hijack()
+}
+
+func (w *compressWriter) Status() int {
+	return w.status
+}
+
+func (w *compressWriter) Size() int {
+	return w.size + len(w.buf)
+}
+
+func (w *compressWriter) Written() bool {
+	return w.decided || w.wroteCode
+}
+
+// 向响应头Vary中添加value(已存在时不重复添加)
+func addVary(header http.Header, value string) {
+	for _, vary := range header.Values("Vary") {
+		for _, v := range strings.Split(vary, ",") {
+			v = strings.TrimSpace(v)
+			if v == "*" || strings.EqualFold(v, value) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", value)
+}
+
+// Decompress 请求体解压中间件：请求头Content-Encoding为gzip或deflate(zlib格式)时解压请求体，不支持的编码返回415
+// 解压后的请求体同样受MaxBodyBytes限制(超过时返回413)，没有设置MaxBodyBytes时限制为32M，需要更大的请求体时设置MaxBodyBytes
+func Decompress(next HandlerFunc) HandlerFunc {
+	return func(ctx *Context) {
+		r := ctx.R
+		var reader io.ReadCloser
+		switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+		case "", "identity":
+			next(ctx)
+			return
+		case "gzip", "x-gzip":
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				invalidEncodedBody(ctx, "gzip")
+				return
+			}
+			reader = zr
+		case "deflate":
+			zr, err := zlib.NewReader(r.Body)
+			if err != nil {
+				invalidEncodedBody(ctx, "deflate")
+				return
+			}
+			reader = zr
+		default:
+			ctx.Abort()
+			ctx.String(http.StatusUnsupportedMediaType, "unsupported Content-Encoding: %s\n", encoding)
+			return
+		}
+		body := r.Body
+		// 原始请求体的限制只约束压缩后的大小，解压后的大小需要再次限制
+		r.Body = &maxBytesReader{ctx: ctx, rc: &decompressReader{ReadCloser: reader, body: body}, defaultLimit: defaultDecompressMaxBytes}
+		r.Header.Del("Content-Encoding")
+		r.Header.Del("Content-Length")
+		r.ContentLength = -1
+		next(ctx)
+	}
+}
+
+// 请求体无法解压时返回400(压缩后的请求体已经超过MaxBodyBytes时由请求处理结束后的检查返回413)
+func invalidEncodedBody(ctx *Context, encoding string) {
+	ctx.Abort()
+	if !ctx.bodyTooLarge {
+		ctx.String(http.StatusBadRequest, "invalid %s request body\n", encoding)
+	}
+}
+
+// 关闭时同时关闭解压reader和原始请求体
+type decompressReader struct {
+	io.ReadCloser
+	body io.ReadCloser
+}
+
+func (r *decompressReader) Close() error {
+	r.ReadCloser.Close()
+	return r.body.Close()
+}
@@ -0,0 +1,146 @@
+package qiaomu
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("qiaomu", 500)
+	engine := New()
+	engine.Use(Compress)
+	group := engine.Group("data")
+	group.Get("/large", func(ctx *Context) {
+		ctx.String(http.StatusOK, large)
+	})
+	group.Get("/small", func(ctx *Context) {
+		ctx.JSON(http.StatusOK, map[string]string{"msg": "ok"})
+	})
+	group.Get("/png", func(ctx *Context) {
+		ctx.W.Header().Set("Content-Type", "image/png")
+		ctx.W.Write([]byte(large))
+	})
+
+	var testcases = []struct {
+		path           string
+		acceptEncoding string
+		rangeHeader    string
+		encoding       string
+		vary           bool
+	}{
+		{"/data/large", "gzip, deflate", "", "gzip", true},
+		{"/data/large", "gzip;q=0.5, deflate", "", "deflate", true},
+		{"/data/large", "*", "", "gzip", true},
+		{"/data/large", "gzip;q=0", "", "", true},
+		{"/data/large", "", "", "", true},
+		{"/data/large", "gzip", "bytes=0-10", "", false},
+		{"/data/small", "gzip", "", "", true},
+		{"/data/png", "gzip", "", "", false},
+	}
+	for _, testcase := range testcases {
+		req := httptest.NewRequest(http.MethodGet, testcase.path, nil)
+		req.Header.Set("Accept-Encoding", testcase.acceptEncoding)
+		if testcase.rangeHeader != "" {
+			req.Header.Set("Range", testcase.rangeHeader)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		name := testcase.path + " " + testcase.acceptEncoding
+		if got := w.Header().Get("Content-Encoding"); got != testcase.encoding {
+			t.Errorf("%s: got Content-Encoding %q, want %q", name, got, testcase.encoding)
+		}
+		if got := w.Header().Get("Vary") == "Accept-Encoding"; got != testcase.vary {
+			t.Errorf("%s: got Vary %q", name, w.Header().Get("Vary"))
+		}
+		if testcase.encoding != "" {
+			var zr io.Reader
+			var err error
+			if testcase.encoding == "gzip" {
+				zr, err = gzip.NewReader(w.Body)
+			} else {
+				zr, err = zlib.NewReader(w.Body)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(zr)
+			if string(body) != large {
+				t.Errorf("%s: decompressed body mismatch", name)
+			}
+		}
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	engine := New()
+	engine.Use(Decompress)
+	engine.Group("data").Post("/echo", func(ctx *Context) {
+		body, err := io.ReadAll(ctx.R.Body)
+		if err != nil {
+			return
+		}
+		ctx.String(http.StatusOK, string(body))
+	})
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(`{"name":"qiaomu"}`))
+	zw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/data/echo", &buf)
+	req.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Body.String() != `{"name":"qiaomu"}` {
+		t.Errorf("got body %q", w.Body.String())
+	}
+
+	buf.Reset()
+	zlw := zlib.NewWriter(&buf)
+	zlw.Write([]byte(`{"name":"deflate"}`))
+	zlw.Close()
+	req = httptest.NewRequest(http.MethodPost, "/data/echo", &buf)
+	req.Header.Set("Content-Encoding", "deflate")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Body.String() != `{"name":"deflate"}` {
+		t.Errorf("got deflate body %q", w.Body.String())
+	}
+
+	// 没有设置MaxBodyBytes时解压后的请求体同样有默认限制
+	buf.Reset()
+	zw = gzip.NewWriter(&buf)
+	zw.Write(make([]byte, defaultDecompressMaxBytes+1))
+	zw.Close()
+	bomb := buf.Bytes()
+	req = httptest.NewRequest(http.MethodPost, "/data/echo", bytes.NewReader(bomb))
+	req.Header.Set("Content-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("default limit: got status %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+
+	// 解压后超过MaxBodyBytes时返回413
+	engine.MaxBodyBytes = 4 << 10
+	req = httptest.NewRequest(http.MethodPost, "/data/echo", bytes.NewReader(bomb))
+	req.Header.Set("Content-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("gzip bomb: got status %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/data/echo", strings.NewReader("x"))
+	req.Header.Set("Content-Encoding", "zstd")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusUnsupportedMediaType)
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"flag"
 	"os"
+	"sync"
 
 	"github.com/BurntSushi/toml"
 	qlog "github.com/qingbo1011/qiaomu/log"
@@ -19,12 +20,18 @@ type QueenConfig struct {
 	Template map[string]any
 }
 
-func init() {
-	loadToml()
+var configFile = flag.String("conf", "conf/app.toml", "app config file")
+
+var loadOnce sync.Once
+
+// Load 解析命令行参数并读取配置文件(只在第一次调用时读取)，返回Conf
+// 不在init中读取：go test生成的测试程序在init之后才注册-test.*参数，init中解析会因未定义的参数退出
+func Load() *QueenConfig {
+	loadOnce.Do(loadToml)
+	return Conf
 }
 
 func loadToml() {
-	configFile := flag.String("conf", "conf/app.toml", "app config file")
 	flag.Parse()
 	if _, err := os.Stat(*configFile); err != nil {
 		Conf.logger.Info("conf/app.toml file not load，because not exist")
@@ -0,0 +1,228 @@
+package qiaomu
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// 路由组的Host限制(如api.example.com、*.example.com、:tenant.example.com)
+type hostPattern struct {
+	pattern string
+	labels  []string
+}
+
+// 请求头限制，value为空时只要求请求头存在
+type headerConstraint struct {
+	key   string
+	value string
+}
+
+func newHostPattern(pattern string) *hostPattern {
+	pattern = strings.ToLower(pattern)
+	labels := strings.Split(pattern, ".")
+	for i, label := range labels {
+		if label == "" || label == ":" || (label == "*" && i != 0) {
+			panic(errors.New(fmt.Sprintf("host pattern [%s] is invalid", pattern)))
+		}
+	}
+	return &hostPattern{pattern: pattern, labels: labels}
+}
+
+// 匹配Host，*匹配的子域名以subdomain为键、:name匹配的部分以name为键追加到params中
+func (h *hostPattern) match(host string, params *Params) bool {
+	if host == "" {
+		return false
+	}
+	hostLabels := strings.Split(host, ".")
+	wildcard := h.labels[0] == "*"
+	if len(hostLabels) < len(h.labels) || (!wildcard && len(hostLabels) != len(h.labels)) {
+		return false
+	}
+	// 从右向左逐段比较，*匹配剩余的一段或多段
+	offset := len(hostLabels) - len(h.labels)
+	for i := len(h.labels) - 1; i >= 0; i-- {
+		label, hostLabel := h.labels[i], hostLabels[i+offset]
+		switch {
+		case i == 0 && wildcard:
+			*params = append(*params, Param{Key: "subdomain", Value: strings.Join(hostLabels[:offset+1], ".")})
+		case label[0] == ':':
+			*params = append(*params, Param{Key: label[1:], Value: hostLabel})
+		case label != hostLabel:
+			return false
+		}
+	}
+	return true
+}
+
+// Host 将路由组限制为只处理Host匹配pattern的请求(子路由组同样受限制)，只对之后注册的路由生效
+// pattern支持*.example.com(子域名通过ctx.Param("subdomain")获取)和:tenant.example.com(通过ctx.Param("tenant")获取)
+func (r *routerGroup) Host(pattern string) *routerGroup {
+	r.host = newHostPattern(pattern)
+	return r
+}
+
+// Header 将路由组限制为只处理请求头key的值为value的请求(value为空时只要求请求头存在)，如Header("Accept-Version", "v2")
+// 只对之后注册的路由生效
+func (r *routerGroup) Header(key, value string) *routerGroup {
+	r.headers = append(r.headers, headerConstraint{key: http.CanonicalHeaderKey(key), value: value})
+	return r
+}
+
+// Host 创建只处理Host匹配pattern的请求的路由组(路由前缀为空)
+func (e *Engine) Host(pattern string) *routerGroup {
+	return e.Group("").Host(pattern)
+}
+
+// Header 将路由限制为只处理请求头key的值为value的请求(value为空时只要求请求头存在)
+// 同一路由可以按不同的限制注册多次，有限制的路由优先于没有限制的路由
+func (rt *Route) Header(key, value string) *Route {
+	rt.headers = append(rt.headers, headerConstraint{key: http.CanonicalHeaderKey(key), value: value})
+	router := rt.group.router
+	// 最后注册的路由在下一个路由注册(或启动服务)时才检查是否重复，之前注册的路由修改限制后立即检查
+	if router.pending != rt {
+		router.checkDuplicate(rt)
+	}
+	return rt
+}
+
+// 注册路由时保存所在路由组(包括父路由组)的Host和请求头限制，之后修改路由组的限制不影响已注册的路由
+func (rt *Route) snapshotConstraints() {
+	for g := rt.group; g != nil; g = g.parent {
+		if g.host != nil {
+			rt.hosts = append(rt.hosts, g.host)
+		}
+		rt.groupHeaders = append(rt.groupHeaders, g.headers...)
+	}
+}
+
+// 路由或其所在的路由组(包括父路由组)是否有Host或请求头限制
+func (rt *Route) hasConstraints() bool {
+	return len(rt.headers) > 0 || len(rt.groupHeaders) > 0 || len(rt.hosts) > 0
+}
+
+// 请求是否满足路由的所有限制，满足时Host中匹配到的参数追加到ctx.params中
+func (rt *Route) matchConstraints(ctx *Context) bool {
+	if !matchHeaders(ctx.R, rt.headers) || !matchHeaders(ctx.R, rt.groupHeaders) {
+		return false
+	}
+	n := len(ctx.params)
+	for _, host := range rt.hosts {
+		if !host.match(requestHost(ctx.Host()), &ctx.params) {
+			ctx.params = ctx.params[:n]
+			return false
+		}
+	}
+	return true
+}
+
+// 用于判断重复注册：Host和请求头限制(包括路由级的)都相同的同一路由视为重复
+func (rt *Route) constraintKey() string {
+	var sb strings.Builder
+	for _, host := range rt.hosts {
+		sb.WriteString(host.pattern + ";")
+	}
+	headers := rt.headerConstraints()
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		sb.WriteString("|" + key + "=" + headers[key])
+	}
+	return sb.String()
+}
+
+// 路由的Host限制(最内层路由组的)
+func (rt *Route) hostPattern() string {
+	if len(rt.hosts) > 0 {
+		return rt.hosts[0].pattern
+	}
+	return ""
+}
+
+// 路由的所有请求头限制
+func (rt *Route) headerConstraints() map[string]string {
+	headers := make(map[string]string)
+	for i := len(rt.groupHeaders) - 1; i >= 0; i-- {
+		headers[rt.groupHeaders[i].key] = rt.groupHeaders[i].value
+	}
+	for _, header := range rt.headers {
+		headers[header.key] = header.value
+	}
+	if len(headers) == 0 {
+		return nil
+	}
+	return headers
+}
+
+// 从候选路由中选出满足限制的路由：有多个时选限制最具体的(见specificity)，相同时先注册的优先，都不满足时使用没有限制的路由
+func matchConstraints(ctx *Context, routes []*Route) (*Route, bool) {
+	var fallback, best *Route
+	bestScore := -1
+	n := len(ctx.params)
+	for _, rt := range routes {
+		if !rt.hasConstraints() {
+			if fallback == nil {
+				fallback = rt
+			}
+			continue
+		}
+		if score := rt.specificity(); score > bestScore && rt.matchConstraints(ctx) {
+			best, bestScore = rt, score
+		}
+		ctx.params = ctx.params[:n]
+	}
+	if best != nil {
+		// 重新匹配一次，追加选中路由在Host中匹配到的参数
+		best.matchConstraints(ctx)
+		return best, true
+	}
+	return fallback, fallback != nil
+}
+
+// 限制的具体程度：Host中每个固定的段计2分，*和:name计1分；要求请求头等于指定值计2分，只要求存在计1分
+func (rt *Route) specificity() int {
+	score := 0
+	for _, host := range rt.hosts {
+		for _, label := range host.labels {
+			if label == "*" || label[0] == ':' {
+				score++
+			} else {
+				score += 2
+			}
+		}
+	}
+	for _, headers := range [][]headerConstraint{rt.groupHeaders, rt.headers} {
+		for _, header := range headers {
+			if header.value == "" {
+				score++
+			} else {
+				score += 2
+			}
+		}
+	}
+	return score
+}
+
+func matchHeaders(r *http.Request, headers []headerConstraint) bool {
+	for _, header := range headers {
+		values, ok := r.Header[header.key]
+		if !ok || (header.value != "" && (len(values) == 0 || values[0] != header.value)) {
+			return false
+		}
+	}
+	return true
+}
+
+// 去掉Host中的端口并转为小写
+func requestHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
@@ -3,12 +3,11 @@ package qiaomu
 import (
 	"errors"
 	"html/template"
-	"io"
 	"log"
+	"math"
 	"mime/multipart"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -21,8 +20,10 @@ import (
 
 const defaultMultipartMemory = 32 << 20 // 32M (ParseMultipartForm方法能支持的最大内存)
 
+const abortIndex int = math.MaxInt >> 1 // 处理链被终止后index的值
+
 type Context struct {
-	W                     http.ResponseWriter
+	W                     ResponseWriter // 记录状态码和响应体大小的http.ResponseWriter
 	R                     *http.Request
 	StatusCode            int
 	engine                *Engine
@@ -34,10 +35,88 @@ type Context struct {
 	Keys                  map[string]any
 	mu                    sync.RWMutex
 	sameSite              http.SameSite
+	params                Params
+	handlers              []HandlerFunc // 本次请求的处理链(中间件和路由处理函数)
+	index                 int           // 当前执行到的处理函数在handlers中的位置
+	writer                responseWriter
+	etagMode              ETagMode // 渲染输出的ETag生成方式(默认为Engine.ETag)
+	requestID             string   // 请求ID(见RequestID中间件)
+	maxBodyBytes          int64    // 请求体大小限制(默认为Engine.MaxBodyBytes)
+	bodyTooLarge          bool     // 读取请求体时超过了大小限制
+}
+
+// 从对象池中取出Context后重置其状态，避免上一个请求的数据残留
+func (c *Context) reset(w http.ResponseWriter, r *http.Request) {
+	c.writer.reset(w)
+	c.W = &c.writer
+	c.R = r
+	c.StatusCode = 0
+	c.queryCache = nil
+	c.formCache = nil
+	c.DisallowUnknownFields = false
+	c.IsValidate = false
+	c.Keys = nil
+	c.sameSite = 0
+	c.params = c.params[:0]
+	c.handlers = c.handlers[:0]
+	c.index = -1
+	c.etagMode = ETagNone
+	c.requestID = ""
+	c.maxBodyBytes = 0
+	c.bodyTooLarge = false
+	if c.engine != nil {
+		c.etagMode = c.engine.ETag
+		if c.engine.MaxBodyBytes > 0 {
+			c.maxBodyBytes = c.engine.MaxBodyBytes
+			c.limitBody()
+		}
+	}
+}
+
+// Next 执行处理链中的后续处理函数(只应在中间件中调用)
+func (c *Context) Next() {
+	c.index++
+	for c.index < len(c.handlers) {
+		c.handlers[c.index](c)
+		c.index++
+	}
+}
+
+// Abort 终止处理链，当前处理函数之后的处理函数不再执行(已经在执行中的外层中间件会继续完成)
+func (c *Context) Abort() {
+	c.index = abortIndex
+}
+
+// IsAborted 处理链是否已被终止
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+// AbortWithStatus 终止处理链并写入响应状态码
+func (c *Context) AbortWithStatus(code int) {
+	c.Abort()
+	c.W.WriteHeader(code)
+	c.StatusCode = code
+}
+
+// AbortWithStatusJSON 终止处理链并以JSON格式返回数据
+func (c *Context) AbortWithStatusJSON(code int, data any) error {
+	c.Abort()
+	return c.JSON(code, data)
 }
 
 // Render 渲染统一处理
+// GET/HEAD请求的200响应会做条件请求处理：开启ETag时根据渲染结果生成ETag，客户端缓存有效(见IsFresh)时返回304
 func (c *Context) Render(statusCode int, r render.Render) error {
+	if c.conditionalRender(statusCode, r) {
+		if c.etagMode != ETagNone && c.W.Header().Get("ETag") == "" {
+			return c.renderWithETag(statusCode, r)
+		}
+		if c.IsFresh() {
+			c.notModified()
+			return nil
+		}
+	}
 	err := r.Render(c.W, statusCode)
 	c.StatusCode = statusCode
 	// 多次调用WriteHeader会产生这样的警告 superfluous response.WriteHeader
@@ -52,7 +131,7 @@ func (c *Context) HTML(status int, html string) error {
 // HTMLTemplate HTML页面渲染：模板支持
 func (c *Context) HTMLTemplate(name string, data any, filenames ...string) error {
 	c.W.Header().Set("Content-Type", "text/html; charset=utf-8")
-	t := template.New(name)
+	t := template.New(name).Funcs(c.engine.funcMap)
 	t, err := t.ParseFiles(filenames...)
 	if err != nil {
 		return err
@@ -64,7 +143,7 @@ func (c *Context) HTMLTemplate(name string, data any, filenames ...string) error
 // HTMLTemplateGlob 通过go html/template包自带的ParseGlob方法，实现filename的匹配模式
 func (c *Context) HTMLTemplateGlob(name string, data any, pattern string) error {
 	c.W.Header().Set("Content-Type", "text/html; charset=utf-8")
-	t := template.New(name)
+	t := template.New(name).Funcs(c.engine.funcMap)
 	t, err := t.ParseGlob(pattern)
 	if err != nil {
 		return err
@@ -146,6 +225,57 @@ func (c *Context) HandleWithError(statusCode int, obj any, err error) {
 	c.JSON(statusCode, obj)
 }
 
+// Param 获取路由参数(以/user/get/:id 为例，请求/user/get/1时Param("id")返回1，参数不存在返回空字符串)
+func (c *Context) Param(key string) string {
+	return c.params.ByName(key)
+}
+
+// GetParam 获取路由参数，并返回该参数是否存在
+func (c *Context) GetParam(key string) (string, bool) {
+	return c.params.Get(key)
+}
+
+// Params 获取本次请求匹配到的全部路由参数(** 匹配到的剩余路径的key为**)
+func (c *Context) Params() Params {
+	return c.params
+}
+
+// ParamInt 获取路由参数并转换为int类型
+func (c *Context) ParamInt(key string) (int, error) {
+	value, ok := c.params.Get(key)
+	if !ok {
+		return 0, errors.New("param [" + key + "] is not exist")
+	}
+	return strconv.Atoi(value)
+}
+
+// ParamInt64 获取路由参数并转换为int64类型
+func (c *Context) ParamInt64(key string) (int64, error) {
+	value, ok := c.params.Get(key)
+	if !ok {
+		return 0, errors.New("param [" + key + "] is not exist")
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// ParamFloat64 获取路由参数并转换为float64类型
+func (c *Context) ParamFloat64(key string) (float64, error) {
+	value, ok := c.params.Get(key)
+	if !ok {
+		return 0, errors.New("param [" + key + "] is not exist")
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// ParamBool 获取路由参数并转换为bool类型
+func (c *Context) ParamBool(key string) (bool, error) {
+	value, ok := c.params.Get(key)
+	if !ok {
+		return false, errors.New("param [" + key + "] is not exist")
+	}
+	return strconv.ParseBool(value)
+}
+
 // GetQuery 获取query参数 (以/user/add?name=李四 为例，可以获取到name参数为李四)
 func (c *Context) GetQuery(key string) string {
 	c.initQueryCache()
@@ -215,7 +345,7 @@ func (c *Context) get(cache map[string][]string, key string) (map[string]string,
 // 初始化formCache
 func (c *Context) initPostFormCache() {
 	if c.R != nil {
-		if err := c.R.ParseMultipartForm(defaultMultipartMemory); err != nil {
+		if err := c.R.ParseMultipartForm(c.multipartMemory()); err != nil {
 			if !errors.Is(err, http.ErrNotMultipart) {
 				log.Println(err)
 			}
@@ -278,27 +408,9 @@ func (c *Context) FormFiles(name string) []*multipart.FileHeader {
 	return multipartForm.File[name]
 }
 
-// SaveUploadedFile 文件参数处理，支持用户自定义文件要上传的目录
-func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
-	src, err := file.Open()
-	if err != nil {
-		return err
-	}
-	defer src.Close()
-
-	out, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	_, err = io.Copy(out, src)
-	return err
-}
-
 // MultipartForm 将form表单类型参数整个提取为*multipart.Form类型
 func (c *Context) MultipartForm() (*multipart.Form, error) {
-	err := c.R.ParseMultipartForm(defaultMultipartMemory)
+	err := c.R.ParseMultipartForm(c.multipartMemory())
 	return c.R.MultipartForm, err
 }
 
@@ -315,9 +427,53 @@ func (c *Context) BindXML(obj any) error {
 	return c.MustBindWith(obj, bind.XML)
 }
 
+// Bind 根据请求方式和Content-Type选择绑定方式(见bind.Default)，JSON绑定使用Context的DisallowUnknownFields和IsValidate设置
+func (c *Context) Bind(obj any) error {
+	b := bind.Default(c.R.Method, c.R.Header.Get("Content-Type"))
+	if b == bind.JSON {
+		json := bind.JSON
+		json.DisallowUnknownFields = c.DisallowUnknownFields
+		json.IsValidate = c.IsValidate
+		b = json
+	}
+	return c.MustBindWith(obj, b)
+}
+
+// BindQuery 绑定查询参数(form标签)
+func (c *Context) BindQuery(obj any) error {
+	return c.MustBindWith(obj, bind.Query)
+}
+
+// BindHeader 绑定请求头(header标签)
+func (c *Context) BindHeader(obj any) error {
+	return c.MustBindWith(obj, bind.Header)
+}
+
+// BindUri 绑定路由参数(uri标签)，如果绑定出现错误，终止请求并返回400状态码
+func (c *Context) BindUri(obj any) error {
+	if err := c.ShouldBindUri(obj); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return err
+	}
+	return nil
+}
+
+// ShouldBindUri 绑定路由参数(uri标签)，如果绑定出现错误，返回错误并由开发者自行处理错误和请求
+func (c *Context) ShouldBindUri(obj any) error {
+	params := make(map[string][]string, len(c.params))
+	for _, param := range c.params {
+		params[param.Key] = append(params[param.Key], param.Value)
+	}
+	return bind.Uri.BindUri(params, obj)
+}
+
 // MustBindWith 如果绑定出现错误，终止请求并返回400状态码
 func (c *Context) MustBindWith(obj any, bind bind.Binding) error {
 	if err := c.ShouldBind(obj, bind); err != nil {
+		if errors.Is(err, ErrBodyTooLarge) {
+			c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+			return err
+		}
 		c.W.WriteHeader(http.StatusBadRequest)
 		return err
 	}
@@ -325,8 +481,21 @@ func (c *Context) MustBindWith(obj any, bind bind.Binding) error {
 }
 
 // ShouldBind 如果绑定出现错误，返回错误并由开发者自行处理错误和请求
-func (c *Context) ShouldBind(obj any, bind bind.Binding) error {
-	return bind.Bind(c.R, obj)
+func (c *Context) ShouldBind(obj any, b bind.Binding) error {
+	// 表单绑定按Engine.MaxMultipartMemory解析multipart表单
+	if b == bind.Form || b == bind.FormMultipart {
+		if err := c.R.ParseMultipartForm(c.multipartMemory()); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			if c.bodyTooLarge {
+				return ErrBodyTooLarge
+			}
+			return err
+		}
+	}
+	err := b.Bind(c.R, obj)
+	if c.bodyTooLarge {
+		return ErrBodyTooLarge
+	}
+	return err
 }
 
 // Set 在Context中设置信息
@@ -0,0 +1,272 @@
+package qiaomu
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qingbo1011/qiaomu/render"
+)
+
+func TestSSEvent(t *testing.T) {
+	engine := New()
+	engine.Group("order").Get("/events", func(ctx *Context) {
+		ctx.SSEvent("status", "paid")
+		ctx.Render(http.StatusOK, &render.SSEvent{Id: "2", Data: map[string]int{"id": 1}})
+		ctx.SSEComment("heartbeat")
+		ctx.SSEvent("", "line1\nline2")
+	})
+
+	w := performRequest(engine, http.MethodGet, "/order/events")
+	if w.Header().Get("Content-Type") != "text/event-stream" || !w.Flushed {
+		t.Errorf("got Content-Type %q, flushed %v", w.Header().Get("Content-Type"), w.Flushed)
+	}
+	want := "event: status\ndata: paid\n\n" +
+		"id: 2\ndata: {\"id\":1}\n\n" +
+		": heartbeat\n\n" +
+		"data: line1\ndata: line2\n\n"
+	if w.Body.String() != want {
+		t.Errorf("got body %q, want %q", w.Body.String(), want)
+	}
+}
+
+func TestSSEStream(t *testing.T) {
+	engine := New()
+	var lastEventID string
+	engine.Group("order").Get("/stream", func(ctx *Context) {
+		lastEventID = ctx.LastEventID()
+		events := make(chan render.SSEvent)
+		go func() {
+			for i := 0; i < 3; i++ {
+				select {
+				case events <- render.SSEvent{Event: "tick", Data: "x"}:
+				case <-ctx.R.Context().Done():
+					return
+				}
+			}
+		}()
+		ctx.SSEStream(events, 10*time.Millisecond)
+	})
+
+	server := httptest.NewServer(engine)
+	defer server.Close()
+	reqCtx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(reqCtx, http.MethodGet, server.URL+"/order/stream", nil)
+	req.Header.Set("Last-Event-ID", "41")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	// 读到3个事件和至少一次心跳后断开连接
+	var received string
+	buf := make([]byte, 1024)
+	for strings.Count(received, "event: tick") < 3 || !strings.Contains(received, ": heartbeat") {
+		n, err := resp.Body.Read(buf)
+		if err != nil {
+			t.Fatalf("got %q, err %v", received, err)
+		}
+		received += string(buf[:n])
+	}
+	cancel()
+	if lastEventID != "41" {
+		t.Errorf("got Last-Event-ID %q", lastEventID)
+	}
+}
+
+func TestStream(t *testing.T) {
+	engine := New()
+	engine.Group("order").Get("/stream", func(ctx *Context) {
+		i := 0
+		ctx.Stream(func(w io.Writer) bool {
+			i++
+			io.WriteString(w, "chunk\n")
+			return i < 3
+		})
+	})
+	w := performRequest(engine, http.MethodGet, "/order/stream")
+	if w.Body.String() != "chunk\nchunk\nchunk\n" {
+		t.Errorf("got body %q", w.Body.String())
+	}
+}
+
+func TestResponseWriter(t *testing.T) {
+	var buf strings.Builder
+	engine := New()
+	engine.Use(func(next HandlerFunc) HandlerFunc {
+		return LoggingWithConfig(LoggingConfig{
+			out: &buf,
+			Formatter: func(params *LogFormatterParams) string {
+				return fmt.Sprintf("%s %d %d\n", params.Path, params.StatusCode, params.BodySize)
+			},
+		}, next)
+	})
+	group := engine.Group("user")
+	group.Get("/fprint", func(ctx *Context) {
+		fmt.Fprint(ctx.W, "hello")
+	})
+	// 路由级的MiddlewareFunc在全局中间件外层，这里作为Next风格的中间件使用，使日志中间件能记录401
+	group.Handle("/basic", http.MethodGet, WrapMiddleware((&Accounts{Users: map[string]string{"qingbo": "1234"}}).BasicAuth), func(ctx *Context) {})
+	group.Get("/twice", func(ctx *Context) {
+		ctx.W.WriteHeader(http.StatusCreated)
+		ctx.W.WriteHeader(http.StatusInternalServerError)
+		if !ctx.W.Written() || ctx.W.Status() != http.StatusCreated {
+			t.Errorf("got written %v status %d", ctx.W.Written(), ctx.W.Status())
+		}
+	})
+
+	performRequest(engine, http.MethodGet, "/user/fprint")
+	performRequest(engine, http.MethodGet, "/user/basic")
+	w := performRequest(engine, http.MethodGet, "/user/twice")
+	if w.Code != http.StatusCreated {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusCreated)
+	}
+	want := "/user/fprint 200 5\n/user/basic 401 0\n/user/twice 201 0\n"
+	if buf.String() != want {
+		t.Errorf("got log %q, want %q", buf.String(), want)
+	}
+}
+
+func TestETag(t *testing.T) {
+	modified := time.Date(2023, 5, 1, 8, 0, 0, 0, time.UTC)
+	engine := New()
+	engine.ETag = ETagWeak
+	goods := engine.Group("goods")
+	goods.Get("/list", func(ctx *Context) {
+		ctx.JSON(http.StatusOK, []string{"apple", "pear"})
+	})
+	goods.Get("/strong", func(ctx *Context) {
+		ctx.JSON(http.StatusOK, []string{"apple", "pear"})
+	}, ETag(ETagStrong))
+	goods.Get("/modified", func(ctx *Context) {
+		ctx.SetLastModified(modified)
+		ctx.String(http.StatusOK, "catalog")
+	}, ETag(ETagNone))
+
+	request := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("/goods/list", nil)
+	weak := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || !strings.HasPrefix(weak, `W/"`) || w.Body.String() != `["apple","pear"]` {
+		t.Fatalf("got %d ETag %q body %q", w.Code, weak, w.Body.String())
+	}
+	if w := request("/goods/list", map[string]string{"If-None-Match": weak}); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("If-None-Match: got %d %q", w.Code, w.Body.String())
+	}
+	if w := request("/goods/list", map[string]string{"If-None-Match": `W/"other"`}); w.Code != http.StatusOK {
+		t.Errorf("If-None-Match mismatch: got %d", w.Code)
+	}
+
+	w = request("/goods/strong", nil)
+	strong := w.Header().Get("ETag")
+	if strings.HasPrefix(strong, "W/") || len(strong) != 34 {
+		t.Errorf("got strong ETag %q", strong)
+	}
+	if w := request("/goods/strong", map[string]string{"If-None-Match": strong}); w.Code != http.StatusNotModified {
+		t.Errorf("strong If-None-Match: got %d", w.Code)
+	}
+
+	w = request("/goods/modified", map[string]string{"If-Modified-Since": modified.Add(time.Hour).Format(http.TimeFormat)})
+	if w.Code != http.StatusNotModified || w.Header().Get("ETag") != "" {
+		t.Errorf("If-Modified-Since: got %d ETag %q", w.Code, w.Header().Get("ETag"))
+	}
+	w = request("/goods/modified", map[string]string{"If-Modified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)})
+	if w.Code != http.StatusOK || w.Body.String() != "catalog" {
+		t.Errorf("If-Modified-Since before: got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	engine := New()
+	if err := engine.SetTrustedProxies([]string{"10.0.0.0/8", "192.168.1.10"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.SetTrustedProxies([]string{"10.0.0.0/8", "not-an-ip"}); err == nil {
+		t.Error("want error for invalid proxy")
+	}
+	engine.Group("user").Get("/ip", func(ctx *Context) {
+		ctx.String(http.StatusOK, ctx.ClientIP()+" "+ctx.Scheme()+" "+ctx.Host())
+	})
+
+	tests := []struct {
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{"203.0.113.5:1234", map[string]string{"X-Forwarded-For": "1.1.1.1", "X-Forwarded-Proto": "https"}, "203.0.113.5 http example.com"},
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "1.1.1.1, 2.2.2.2, 10.0.0.2", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "api.example.com"}, "2.2.2.2 https api.example.com"},
+		{"192.168.1.10:1234", map[string]string{"X-Real-IP": "3.3.3.3"}, "3.3.3.3 http example.com"},
+		{"10.0.0.1:1234", map[string]string{"Forwarded": `for="[2001:db8::17]:4711";proto=https;host=shop.example.com, for=10.0.0.3`}, "2001:db8::17 https shop.example.com"},
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "10.0.0.5, 10.0.0.6"}, "10.0.0.5 http example.com"},
+		// 客户端伪造的最左边的值被忽略
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "1.1.1.1", "X-Forwarded-Proto": "https, http", "X-Forwarded-Host": "evil.com, shop.example.com"}, "1.1.1.1 http shop.example.com"},
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "1.1.1.1, 10.0.0.2", "X-Forwarded-Proto": "https, http, https", "X-Forwarded-Host": "evil.com, shop.example.com, gw.internal"}, "1.1.1.1 http shop.example.com"},
+		{"10.0.0.1:1234", map[string]string{"Forwarded": `for=6.6.6.6;proto=https;host=evil.com, for=1.1.1.1;proto=http;host=shop.example.com, for=10.0.0.3;host=gw.internal`}, "1.1.1.1 http shop.example.com"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "http://example.com/user/ip", nil)
+		r.RemoteAddr = tt.remote
+		for k, v := range tt.headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		if w.Body.String() != tt.want {
+			t.Errorf("remote %s headers %v: got %q, want %q", tt.remote, tt.headers, w.Body.String(), tt.want)
+		}
+	}
+}
+
+func TestContextBind(t *testing.T) {
+	type goods struct {
+		ID   int64  `uri:"id"`
+		Name string `form:"name" json:"name"`
+		Page int    `form:"page,default=1"`
+	}
+	engine := New()
+	engine.Group("goods").Any("/:id", func(ctx *Context) {
+		var g goods
+		if err := ctx.BindUri(&g); err != nil {
+			return
+		}
+		if err := ctx.Bind(&g); err != nil {
+			return
+		}
+		ctx.JSON(http.StatusOK, g)
+	})
+
+	tests := []struct {
+		method, path, contentType, body string
+		want                            string
+		code                            int
+	}{
+		{http.MethodGet, "/goods/1?name=phone", "", "", `{"ID":1,"name":"phone","Page":1}`, http.StatusOK},
+		{http.MethodPost, "/goods/2", "application/json", `{"name":"pad"}`, `{"ID":2,"name":"pad","Page":0}`, http.StatusOK},
+		{http.MethodPost, "/goods/3?page=2", "application/x-www-form-urlencoded", "name=watch", `{"ID":3,"name":"watch","Page":2}`, http.StatusOK},
+		{http.MethodGet, "/goods/abc", "", "", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			r.Header.Set("Content-Type", tt.contentType)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		if w.Code != tt.code || strings.TrimSpace(w.Body.String()) != tt.want {
+			t.Errorf("%s %s: got %d %q, want %d %q", tt.method, tt.path, w.Code, w.Body.String(), tt.code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package qiaomu
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/qingbo1011/qiaomu/render"
+)
+
+// ETagMode 渲染输出(JSON、XML、String、HTML等)的ETag生成方式
+type ETagMode int
+
+const (
+	ETagNone   ETagMode = iota // 不生成ETag
+	ETagWeak                   // 弱ETag：W/"长度-fnv哈希"，计算快
+	ETagStrong                 // 强ETag：内容的sha256哈希
+)
+
+// ETag 为路由组或路由设置ETag生成方式(覆盖Engine.ETag)
+func ETag(mode ETagMode) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			ctx.etagMode = mode
+			next(ctx)
+		}
+	}
+}
+
+// 根据内容生成ETag
+func generateETag(mode ETagMode, data []byte) string {
+	if mode == ETagStrong {
+		sum := sha256.Sum256(data)
+		return `"` + hex.EncodeToString(sum[:16]) + `"`
+	}
+	h := fnv.New64a()
+	h.Write(data)
+	return fmt.Sprintf(`W/"%x-%x"`, len(data), h.Sum64())
+}
+
+// SetETag 设置响应头ETag(没有引号时自动加上)
+func (c *Context) SetETag(etag string) {
+	if !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+		etag = `"` + etag + `"`
+	}
+	c.W.Header().Set("ETag", etag)
+}
+
+// SetLastModified 设置响应头Last-Modified
+func (c *Context) SetLastModified(t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	c.W.Header().Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+}
+
+// IsFresh 客户端缓存是否仍然有效：根据请求头If-None-Match/If-Modified-Since和响应头ETag/Last-Modified判断，
+// 为true时可以直接返回304(渲染输出会自动处理)
+func (c *Context) IsFresh() bool {
+	r := c.R
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if strings.Contains(r.Header.Get("Cache-Control"), "no-cache") {
+		return false
+	}
+	header := c.W.Header()
+	// 同时存在时以If-None-Match为准
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		etag := header.Get("ETag")
+		if etag == "" {
+			return false
+		}
+		return etagWeakMatch(inm, etag)
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		since, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		lastModified, err := http.ParseTime(header.Get("Last-Modified"))
+		if err != nil {
+			return false
+		}
+		return !lastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// If-None-Match中是否有与etag弱匹配的值(忽略W/前缀)
+func etagWeakMatch(ifNoneMatch, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// 返回304，去掉与响应体相关的响应头
+func (c *Context) notModified() {
+	header := c.W.Header()
+	header.Del("Content-Type")
+	header.Del("Content-Length")
+	c.W.WriteHeader(http.StatusNotModified)
+	c.StatusCode = http.StatusNotModified
+}
+
+// 是否对渲染输出做条件请求处理(只处理GET/HEAD的200响应，SSE等流式输出除外)
+func (c *Context) conditionalRender(statusCode int, r render.Render) bool {
+	if statusCode != http.StatusOK || (c.R.Method != http.MethodGet && c.R.Method != http.MethodHead) {
+		return false
+	}
+	_, isStream := r.(*render.SSEvent)
+	return !isStream
+}
+
+// 渲染到缓冲区后根据内容生成ETag，客户端缓存有效时返回304，否则写入渲染结果
+func (c *Context) renderWithETag(statusCode int, r render.Render) error {
+	w := &bufferedResponseWriter{header: c.W.Header(), status: statusCode}
+	if err := r.Render(w, statusCode); err != nil {
+		return err
+	}
+	c.W.Header().Set("ETag", generateETag(c.etagMode, w.buf.Bytes()))
+	if c.IsFresh() {
+		c.notModified()
+		return nil
+	}
+	c.W.WriteHeader(w.status)
+	c.StatusCode = w.status
+	_, err := c.W.Write(w.buf.Bytes())
+	return err
+}
+
+// 将渲染结果写入缓冲区的http.ResponseWriter(响应头与原ResponseWriter共用)
+type bufferedResponseWriter struct {
+	header http.Header
+	buf    bytes.Buffer
+	status int
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *bufferedResponseWriter) Write(data []byte) (int, error) {
+	return w.buf.Write(data)
+}
@@ -0,0 +1,38 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Header 传递请求ID的请求头
+const Header = "X-Request-ID"
+
+type contextKey struct{}
+
+// Key 请求ID在context.Context中的键
+var Key = contextKey{}
+
+// New 生成请求ID(32位十六进制随机字符串)
+func New() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// NewContext 返回携带请求ID的context
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, Key, id)
+}
+
+// FromContext 获取context中的请求ID，没有时返回空字符串
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(Key).(string)
+	return id
+}
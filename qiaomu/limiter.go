@@ -3,6 +3,7 @@ package qiaomu
 import (
 	"context"
 	"net/http"
+	"sync"
 	"time"
 
 	"golang.org/x/time/rate"
@@ -18,6 +19,54 @@ func Limiter(limit, cap int) MiddlewareFunc {
 			defer cancel()
 			err := li.WaitN(con, 1)
 			if err != nil {
+				ctx.Abort()
+				ctx.String(http.StatusForbidden, "被限流了")
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+// 每个客户端IP的限流器
+type ipLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// 超过该时间没有请求的客户端IP的限流器会被清理
+const ipLimiterIdle = 3 * time.Minute
+
+// LimiterByIP 按客户端IP(ctx.ClientIP，配置可信代理后为代理转发的真实IP)分别限流的中间件
+func LimiterByIP(limit, cap int) MiddlewareFunc {
+	var mu sync.Mutex
+	limiters := make(map[string]*ipLimiter)
+	lastSweep := time.Now()
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			ip := ctx.ClientIP()
+			now := time.Now()
+			mu.Lock()
+			if now.Sub(lastSweep) > ipLimiterIdle {
+				for key, l := range limiters {
+					if now.Sub(l.lastSeen) > ipLimiterIdle {
+						delete(limiters, key)
+					}
+				}
+				lastSweep = now
+			}
+			l, ok := limiters[ip]
+			if !ok {
+				l = &ipLimiter{limiter: rate.NewLimiter(rate.Limit(limit), cap)}
+				limiters[ip] = l
+			}
+			l.lastSeen = now
+			mu.Unlock()
+
+			con, cancel := context.WithTimeout(context.Background(), time.Duration(1)*time.Second)
+			defer cancel()
+			if err := l.limiter.WaitN(con, 1); err != nil {
+				ctx.Abort()
 				ctx.String(http.StatusForbidden, "被限流了")
 				return
 			}
@@ -6,7 +6,6 @@ import (
 	"net"
 	"net/http"
 	"os"
-	"strings"
 	"time"
 )
 
@@ -44,8 +43,10 @@ type LogFormatterParams struct {
 	Request        *http.Request
 	TimeStamp      time.Time
 	StatusCode     int
+	BodySize       int // 响应体字节数
 	Latency        time.Duration
 	ClientIP       net.IP
+	RequestID      string // 请求ID(使用了RequestID中间件时)
 	Method         string
 	Path           string
 	IsDisplayColor bool
@@ -71,20 +72,24 @@ var defaultFormatter = func(params *LogFormatterParams) string {
 	if params.Latency > time.Minute {
 		params.Latency = params.Latency.Truncate(time.Second)
 	}
+	var requestID string
+	if params.RequestID != "" {
+		requestID = " | " + params.RequestID
+	}
 	if params.IsDisplayColor {
-		return fmt.Sprintf("%s [qiaomu] %s |%s %v %s| %s %3d %s |%s %13v %s| %15s  |%s %-7s %s %s %#v %s \n",
+		return fmt.Sprintf("%s [qiaomu] %s |%s %v %s| %s %3d %s |%s %13v %s| %15s  |%s %-7s %s %s %#v %s%s \n",
 			yellow, resetColor, blue, params.TimeStamp.Format("2006/01/02 - 15:04:05"), resetColor,
 			statusCodeColor, params.StatusCode, resetColor,
 			red, params.Latency, resetColor,
 			params.ClientIP,
 			magenta, params.Method, resetColor,
-			cyan, params.Path, resetColor,
+			cyan, params.Path, resetColor, requestID,
 		)
 	}
-	return fmt.Sprintf("[qiaomu] %v | %3d | %13v | %15s |%-7s %#v",
+	return fmt.Sprintf("[qiaomu] %v | %3d | %13v | %15s |%-7s %#v%s",
 		params.TimeStamp.Format("2006/01/02 - 15:04:05"),
 		params.StatusCode,
-		params.Latency, params.ClientIP, params.Method, params.Path,
+		params.Latency, params.ClientIP, params.Method, params.Path, requestID,
 	)
 
 }
@@ -114,10 +119,9 @@ func LoggingWithConfig(conf LoggingConfig, next HandlerFunc) HandlerFunc {
 		next(ctx)
 		stop := time.Now()
 		latency := stop.Sub(start)
-		ip, _, _ := net.SplitHostPort(strings.TrimSpace(ctx.R.RemoteAddr))
-		clientIP := net.ParseIP(ip)
+		clientIP := net.ParseIP(ctx.ClientIP())
 		method := r.Method
-		statusCode := ctx.StatusCode
+		statusCode := ctx.W.Status()
 
 		if raw != "" {
 			path = path + "?" + raw
@@ -125,10 +129,12 @@ func LoggingWithConfig(conf LoggingConfig, next HandlerFunc) HandlerFunc {
 
 		param.TimeStamp = stop
 		param.StatusCode = statusCode
+		param.BodySize = ctx.W.Size()
 		param.Latency = latency
 		param.Path = path
 		param.ClientIP = clientIP
 		param.Method = method
+		param.RequestID = ctx.RequestID()
 		fmt.Fprint(out, formatter(param))
 	}
 }
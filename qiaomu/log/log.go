@@ -153,6 +153,8 @@ func (l *Logger) WithFields(fields Fields) *Logger {
 		Outs:         l.Outs,
 		Level:        l.Level,
 		LoggerFields: fields,
+		logPath:      l.logPath,
+		LogFileSize:  l.LogFileSize,
 	}
 }
 
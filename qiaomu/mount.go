@@ -0,0 +1,61 @@
+package qiaomu
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// WrapH 将http.Handler适配为HandlerFunc
+func WrapH(h http.Handler) HandlerFunc {
+	return func(ctx *Context) {
+		h.ServeHTTP(ctx.W, ctx.R)
+	}
+}
+
+// WrapF 将http.HandlerFunc适配为HandlerFunc
+func WrapF(f http.HandlerFunc) HandlerFunc {
+	return func(ctx *Context) {
+		f(ctx.W, ctx.R)
+	}
+}
+
+// Mount 将http.Handler(如pprof、prometheus的handler或者另一个Engine)挂载到prefix下，处理所有请求方式
+// handler收到的请求路径去掉了路由组前缀和prefix(如Mount("/admin", sub)，请求/admin/user/info时sub收到的路径为/user/info)，
+// 请求会先经过路由组的中间件
+func (r *routerGroup) Mount(prefix string, handler http.Handler, middlewareFunc ...MiddlewareFunc) {
+	if strings.Contains(prefix, ":") || strings.Contains(prefix, "*") {
+		panic(errors.New(fmt.Sprintf("mount prefix [%s] can not contain params or wildcards", prefix)))
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+	mountPath := joinPaths(r.prefix, prefix)
+	handlerName := fmt.Sprintf("%T", handler)
+	serve := func(ctx *Context) {
+		handler.ServeHTTP(ctx.W, stripPrefix(ctx.R, mountPath))
+	}
+	if prefix != "" {
+		r.Any(prefix, serve, middlewareFunc...).handler = handlerName
+	}
+	r.Any(prefix+"/**", serve, middlewareFunc...).handler = handlerName
+}
+
+// 复制请求并去掉路径前缀(同http.StripPrefix)
+func stripPrefix(r *http.Request, prefix string) *http.Request {
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
+	if r2.URL.Path == "" || r2.URL.Path[0] != '/' {
+		r2.URL.Path = "/" + r2.URL.Path
+	}
+	if r.URL.RawPath != "" {
+		r2.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, prefix)
+		if r2.URL.RawPath == "" || r2.URL.RawPath[0] != '/' {
+			r2.URL.RawPath = "/" + r2.URL.RawPath
+		}
+	}
+	return r2
+}
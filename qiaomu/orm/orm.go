@@ -1,6 +1,7 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -31,6 +32,7 @@ type QueenSession struct {
 	updateParam strings.Builder
 	whereParam  strings.Builder
 	whereValues []any
+	ctx         context.Context
 }
 
 // Open 打开DB链接
@@ -92,6 +94,19 @@ func Name(name string) string {
 	return result.String()
 }
 
+// WithContext 设置session执行SQL使用的context(如请求的*qiaomu.Context)，context取消或超时后正在执行的SQL会被中断
+func (s *QueenSession) WithContext(ctx context.Context) *QueenSession {
+	s.ctx = ctx
+	return s
+}
+
+func (s *QueenSession) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
 // Table 设置数据库表的名称
 func (s *QueenSession) Table(name string) *QueenSession {
 	s.tableName = name
@@ -107,15 +122,15 @@ func (s *QueenSession) Insert(data any) (int64, int64, error) {
 	var stmt *sql.Stmt
 	var err error
 	if s.beginTx {
-		stmt, err = s.tx.Prepare(query)
+		stmt, err = s.tx.PrepareContext(s.context(), query)
 	} else {
-		stmt, err = s.db.db.Prepare(query)
+		stmt, err = s.db.db.PrepareContext(s.context(), query)
 	}
 	if err != nil {
 		s.db.logger.Error(err)
 		return -1, -1, err
 	}
-	r, err := stmt.Exec(s.values...)
+	r, err := stmt.ExecContext(s.context(), s.values...)
 	if err != nil {
 		s.db.logger.Error(err)
 		return -1, -1, err
@@ -217,16 +232,16 @@ func (s *QueenSession) InsertBatch(data []any) (int64, int64, error) {
 	var stmt *sql.Stmt
 	var err error
 	if s.beginTx {
-		stmt, err = s.tx.Prepare(sb.String())
+		stmt, err = s.tx.PrepareContext(s.context(), sb.String())
 	} else {
-		stmt, err = s.db.db.Prepare(sb.String())
+		stmt, err = s.db.db.PrepareContext(s.context(), sb.String())
 	}
 
 	if err != nil {
 		s.db.logger.Error(err)
 		return -1, -1, err
 	}
-	r, err := stmt.Exec(s.values...)
+	r, err := stmt.ExecContext(s.context(), s.values...)
 	if err != nil {
 		s.db.logger.Error(err)
 		return -1, -1, err
@@ -292,16 +307,16 @@ func (s *QueenSession) Update(data ...any) (int64, int64, error) {
 		var stmt *sql.Stmt
 		var err error
 		if s.beginTx {
-			stmt, err = s.tx.Prepare(sb.String())
+			stmt, err = s.tx.PrepareContext(s.context(), sb.String())
 		} else {
-			stmt, err = s.db.db.Prepare(sb.String())
+			stmt, err = s.db.db.PrepareContext(s.context(), sb.String())
 		}
 		if err != nil {
 			s.db.logger.Error(err)
 			return -1, -1, err
 		}
 		s.values = append(s.values, s.whereValues...)
-		r, err := stmt.Exec(s.values...)
+		r, err := stmt.ExecContext(s.context(), s.values...)
 		if err != nil {
 			s.db.logger.Error(err)
 			return -1, -1, err
@@ -375,16 +390,16 @@ func (s *QueenSession) Update(data ...any) (int64, int64, error) {
 	var stmt *sql.Stmt
 	var err error
 	if s.beginTx {
-		stmt, err = s.tx.Prepare(sb.String())
+		stmt, err = s.tx.PrepareContext(s.context(), sb.String())
 	} else {
-		stmt, err = s.db.db.Prepare(sb.String())
+		stmt, err = s.db.db.PrepareContext(s.context(), sb.String())
 	}
 	if err != nil {
 		s.db.logger.Error(err)
 		return -1, -1, err
 	}
 	s.values = append(s.values, s.whereValues...)
-	r, err := stmt.Exec(s.values...)
+	r, err := stmt.ExecContext(s.context(), s.values...)
 	if err != nil {
 		s.db.logger.Error(err)
 		return -1, -1, err
@@ -438,15 +453,15 @@ func (s *QueenSession) Delete() (int64, error) {
 	var stmt *sql.Stmt
 	var err error
 	if s.beginTx {
-		stmt, err = s.tx.Prepare(sb.String())
+		stmt, err = s.tx.PrepareContext(s.context(), sb.String())
 	} else {
-		stmt, err = s.db.db.Prepare(sb.String())
+		stmt, err = s.db.db.PrepareContext(s.context(), sb.String())
 	}
 	if err != nil {
 		s.db.logger.Error(err)
 		return 0, err
 	}
-	r, err := stmt.Exec(s.whereValues...)
+	r, err := stmt.ExecContext(s.context(), s.whereValues...)
 	if err != nil {
 		s.db.logger.Error(err)
 		return 0, err
@@ -477,12 +492,12 @@ func (s *QueenSession) Select(data any, fields ...string) ([]any, error) {
 	sb.WriteString(s.whereParam.String())
 	s.db.logger.Info(sb.String())
 
-	stmt, err := s.db.db.Prepare(sb.String())
+	stmt, err := s.db.db.PrepareContext(s.context(), sb.String())
 	if err != nil {
 		s.db.logger.Error(err)
 		return nil, err
 	}
-	rows, err := stmt.Query(s.whereValues...)
+	rows, err := stmt.QueryContext(s.context(), s.whereValues...)
 	if err != nil {
 		s.db.logger.Error(err)
 		return nil, err
@@ -556,12 +571,12 @@ func (s *QueenSession) SelectOne(data any, fields ...string) error {
 	sb.WriteString(s.whereParam.String())
 	s.db.logger.Info(sb.String())
 
-	stmt, err := s.db.db.Prepare(sb.String())
+	stmt, err := s.db.db.PrepareContext(s.context(), sb.String())
 	if err != nil {
 		s.db.logger.Error(err)
 		return err
 	}
-	rows, err := stmt.Query(s.whereValues...)
+	rows, err := stmt.QueryContext(s.context(), s.whereValues...)
 	if err != nil {
 		s.db.logger.Error(err)
 		return err
@@ -630,12 +645,12 @@ func (s *QueenSession) Aggregate(funcName string, field string) (int64, error) {
 	sb.WriteString(s.whereParam.String())
 	s.db.logger.Info(sb.String())
 
-	stmt, err := s.db.db.Prepare(sb.String())
+	stmt, err := s.db.db.PrepareContext(s.context(), sb.String())
 	if err != nil {
 		s.db.logger.Error(err)
 		return 0, err
 	}
-	row := stmt.QueryRow(s.whereValues...)
+	row := stmt.QueryRowContext(s.context(), s.whereValues...)
 	if row.Err() != nil {
 		s.db.logger.Error(err)
 		return 0, err
@@ -753,14 +768,14 @@ func (s *QueenSession) Exec(query string, values ...any) (int64, error) {
 	var stmt *sql.Stmt
 	var err error
 	if s.beginTx {
-		stmt, err = s.tx.Prepare(query)
+		stmt, err = s.tx.PrepareContext(s.context(), query)
 	} else {
-		stmt, err = s.db.db.Prepare(query)
+		stmt, err = s.db.db.PrepareContext(s.context(), query)
 	}
 	if err != nil {
 		return 0, err
 	}
-	r, err := stmt.Exec(values...)
+	r, err := stmt.ExecContext(s.context(), values...)
 	if err != nil {
 		return 0, err
 	}
@@ -776,11 +791,11 @@ func (s *QueenSession) QueryRow(sql string, data any, queryValues ...any) error
 	if t.Kind() != reflect.Pointer {
 		return errors.New("data must be pointer")
 	}
-	stmt, err := s.db.db.Prepare(sql)
+	stmt, err := s.db.db.PrepareContext(s.context(), sql)
 	if err != nil {
 		return err
 	}
-	rows, err := stmt.Query(queryValues...)
+	rows, err := stmt.QueryContext(s.context(), queryValues...)
 	if err != nil {
 		return err
 	}
@@ -827,7 +842,7 @@ func (s *QueenSession) QueryRow(sql string, data any, queryValues ...any) error
 
 // Begin 开启事务
 func (s *QueenSession) Begin() error {
-	tx, err := s.db.db.Begin()
+	tx, err := s.db.db.BeginTx(s.context(), nil)
 	if err != nil {
 		return err
 	}
@@ -65,7 +65,7 @@ func NewTimePool(cap int, expire int) (*Pool, error) {
 
 // NewPoolConf 根据配置文件创建协程池
 func NewPoolConf() (*Pool, error) {
-	cap, ok := config.Conf.Pool["cap"]
+	cap, ok := config.Load().Pool["cap"]
 	if !ok {
 		return nil, errors.New("cap config not exist")
 	}
@@ -5,7 +5,7 @@ import (
 	"strings"
 )
 
-// 前缀树节点
+// 前缀树节点(逐层线性匹配子节点的旧版实现，路由已改用radixNode，这里保留用于基准测试对比)
 type treeNode struct {
 	name       string      // 节点名称，比如/user就是user
 	children   []*treeNode // 子节点
@@ -44,10 +44,12 @@ func (t *treeNode) Put(path string) {
 	t = root
 }
 
-// Get 根据url去匹配到前缀树的节点
-func (t *treeNode) Get(path string) *treeNode {
+// Get 根据url去匹配到前缀树的节点，同时返回匹配过程中提取出的路由参数
+// :name 对应的参数key为name，* 对应的参数key为*，** 对应的参数key为**(值为剩余的全部路径)
+func (t *treeNode) Get(path string) (*treeNode, Params) {
 	strs := strings.Split(path, "/")
 	routerName := ""
+	var params Params
 	for i, name := range strs {
 		if i == 0 {
 			continue
@@ -59,11 +61,14 @@ func (t *treeNode) Get(path string) *treeNode {
 				node.name == "*" ||
 				strings.Contains(node.name, ":") {
 				isMatch = true
+				if node.name != name {
+					params = append(params, Param{Key: strings.TrimPrefix(node.name, ":"), Value: name})
+				}
 				routerName = utils.ConcatenatedString([]string{routerName, "/", node.name})
 				node.routerName = routerName
 				t = node
 				if i == len(strs)-1 {
-					return node
+					return node, params
 				}
 				break
 			}
@@ -73,12 +78,13 @@ func (t *treeNode) Get(path string) *treeNode {
 				// /user/**
 				// /user/get/userInfo // /user/aa/bb
 				if node.name == "**" {
+					params = append(params, Param{Key: node.name, Value: strings.Join(strs[i:], "/")})
 					routerName = utils.ConcatenatedString([]string{routerName, "/", node.name})
 					node.routerName = routerName
-					return node
+					return node, params
 				}
 			}
 		}
 	}
-	return nil
+	return nil, nil
 }
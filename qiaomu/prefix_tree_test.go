@@ -13,12 +13,40 @@ func TestTreeNode(t *testing.T) {
 	root.Put("/user/create/aaa")
 	root.Put("/order/get/aaa")
 
-	node := root.Get("/user/get/1")
+	node, _ := root.Get("/user/get/1")
 	fmt.Println(node) // &{:id [] /user/get/:id}
-	node = root.Get("/user/create/hello")
+	node, _ = root.Get("/user/create/hello")
 	fmt.Println(node) // &{hello [] /user/create/hello}
-	node = root.Get("/user/create/aaa")
+	node, _ = root.Get("/user/create/aaa")
 	fmt.Println(node) // &{aaa [] /user/create/aaa}
-	node = root.Get("/order/get/aaa")
+	node, _ = root.Get("/order/get/aaa")
 	fmt.Println(node) // &{aaa [] /order/get/aaa}
 }
+
+func TestTreeNodeParams(t *testing.T) {
+	root := &treeNode{name: "/", children: make([]*treeNode, 0)}
+
+	root.Put("/user/get/:id")
+	root.Put("/blog/:category/:slug")
+	root.Put("/log/*")
+	root.Put("/static/**")
+
+	var testcases = []struct {
+		path   string
+		params Params
+	}{
+		{"/user/get/1", Params{{Key: "id", Value: "1"}}},
+		{"/blog/go/radix-tree", Params{{Key: "category", Value: "go"}, {Key: "slug", Value: "radix-tree"}}},
+		{"/log/error", Params{{Key: "*", Value: "error"}}},
+		{"/static/css/app.css", Params{{Key: "**", Value: "css/app.css"}}},
+	}
+	for _, testcase := range testcases {
+		node, params := root.Get(testcase.path)
+		if node == nil {
+			t.Fatalf("%s: got nil node", testcase.path)
+		}
+		if fmt.Sprint(params) != fmt.Sprint(testcase.params) {
+			t.Errorf("%s: got %v, want %v", testcase.path, params, testcase.params)
+		}
+	}
+}
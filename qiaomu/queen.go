@@ -1,20 +1,26 @@
 package qiaomu
 
 import (
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/qingbo1011/qiaomu/config"
 	"github.com/qingbo1011/qiaomu/gateway"
+	"github.com/qingbo1011/qiaomu/internal/requestid"
 	qlog "github.com/qingbo1011/qiaomu/log"
 	"github.com/qingbo1011/qiaomu/register"
 	"github.com/qingbo1011/qiaomu/render"
-	"github.com/qingbo1011/qiaomu/utils"
+	"github.com/qingbo1011/qiaomu/websocket"
 )
 
 const (
@@ -25,155 +31,360 @@ type HandlerFunc func(ctx *Context)
 
 type MiddlewareFunc func(handlerFunc HandlerFunc) HandlerFunc
 
+// WrapMiddleware 将MiddlewareFunc风格的中间件适配为处理链中的HandlerFunc
+// 中间件中调用next(ctx)等同于调用ctx.Next()；如果中间件没有调用next，后续的处理函数不再执行(等同于ctx.Abort())
+func WrapMiddleware(middlewareFunc MiddlewareFunc) HandlerFunc {
+	handler := middlewareFunc(func(ctx *Context) {
+		ctx.Next()
+	})
+	return func(ctx *Context) {
+		index := ctx.index
+		handler(ctx)
+		if ctx.index == index {
+			ctx.Abort()
+		}
+	}
+}
+
+// 处理链中的中间件，legacy为true的是MiddlewareFunc风格的中间件
+type middleware struct {
+	name    string
+	handler HandlerFunc
+	legacy  bool
+}
+
+func wrapMiddlewares(middlewareFuncs []MiddlewareFunc) []middleware {
+	middlewares := make([]middleware, 0, len(middlewareFuncs))
+	for _, middlewareFunc := range middlewareFuncs {
+		middlewares = append(middlewares, middleware{name: nameOfFunction(middlewareFunc), handler: WrapMiddleware(middlewareFunc), legacy: true})
+	}
+	return middlewares
+}
+
+func handlerMiddlewares(handlers []HandlerFunc) []middleware {
+	middlewares := make([]middleware, 0, len(handlers))
+	for _, handler := range handlers {
+		middlewares = append(middlewares, middleware{name: nameOfFunction(handler), handler: handler})
+	}
+	return middlewares
+}
+
+// 按执行顺序排列中间件：MiddlewareFunc风格的中间件在外层，按注册的逆序执行(与旧版本的嵌套顺序一致：后注册的在外层，
+// 路由级中间件在路由组中间件外层)；Next风格的中间件在内层，按注册顺序执行
+func orderMiddlewares(middlewares []middleware) []middleware {
+	ordered := make([]middleware, 0, len(middlewares))
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i].legacy {
+			ordered = append(ordered, middlewares[i])
+		}
+	}
+	for _, m := range middlewares {
+		if !m.legacy {
+			ordered = append(ordered, m)
+		}
+	}
+	return ordered
+}
+
+// 将中间件按执行顺序追加到处理链中
+func appendMiddlewares(handlers []HandlerFunc, middlewares []middleware) []HandlerFunc {
+	for _, m := range orderMiddlewares(middlewares) {
+		handlers = append(handlers, m.handler)
+	}
+	return handlers
+}
+
 type router struct {
-	groups []*routerGroup
-	engine *Engine
+	groups      []*routerGroup
+	engine      *Engine
+	tree        *radixNode                     // 所有路由组共用一棵前缀树，以完整路由(路由组前缀+路由)注册
+	routes      map[string]map[string][]*Route // 完整路由 -> method -> 路由(同一路由可以按Host和请求头限制注册多个)
+	namedRoutes map[string]*Route              // 路由名称 -> 路由
+	routeList   []*Route                       // 按注册顺序保存的所有路由
+	pending     *Route                         // 最后注册、尚未检查是否重复的路由(之后还可能通过Route.Header添加限制)
+}
+
+// Route 注册到前缀树中的路由，可以通过Name为路由命名，之后使用Engine.URL生成路由对应的url
+type Route struct {
+	group     *routerGroup
+	name      string // 路由组内的路由(如/info)
+	method    string
+	fullPath  string             // 完整路由(如/user/:id)
+	routeName string             // 路由名称
+	handler   string             // 路由处理函数名称
+	headers   []headerConstraint // 路由级请求头限制
+
+	handlerFunc HandlerFunc  // 路由处理函数
+	middlewares []middleware // 路由级中间件
+
+	hosts        []*hostPattern     // 注册时所在路由组(包括父路由组)的Host限制，从内到外
+	groupHeaders []headerConstraint // 注册时所在路由组(包括父路由组)的请求头限制
+}
+
+// Name 为路由命名(如group.Get("/:id", handler).Name("user.info"))，名称重复时panic
+func (rt *Route) Name(name string) *Route {
+	router := rt.group.router
+	if exist, ok := router.namedRoutes[name]; ok && exist != rt {
+		panic(errors.New(fmt.Sprintf("route name [%s] is already used by [%s %s]", name, exist.method, exist.fullPath)))
+	}
+	if rt.routeName != "" {
+		delete(router.namedRoutes, rt.routeName)
+	}
+	rt.routeName = name
+	router.namedRoutes[name] = rt
+	return rt
 }
 
+// Group 创建路由组(路由组中的路由以/name为前缀)
 func (r *router) Group(name string) *routerGroup {
+	g := r.newGroup(nil, name)
+	g.middlewares = append(g.middlewares, r.engine.middles...)
+	return g
+}
+
+func (r *router) newGroup(parent *routerGroup, name string) *routerGroup {
+	prefix := ""
+	if parent != nil {
+		prefix = parent.prefix
+	}
 	g := &routerGroup{
 		groupName:        name,
-		handlerMap:       make(map[string]map[string]HandlerFunc),
+		prefix:           joinPaths(prefix, strings.TrimSuffix(name, "/")),
+		parent:           parent,
+		router:           r,
 		handlerMethodMap: make(map[string][]string),
-		treeNode: &treeNode{
-			name:     "/",
-			children: make([]*treeNode, 0),
-		},
-		middlewaresFuncMap: make(map[string]map[string][]MiddlewareFunc),
 	}
-	g.Use(r.engine.middles...)
 	r.groups = append(r.groups, g)
 	return g
 }
 
+// 注册完整路由(路由有歧义或者重复注册时直接panic，在启动阶段就暴露问题)
+// 重复注册在下一个路由注册或启动服务时检查，以便注册后通过Route.Header添加的限制参与比较
+func (r *router) addRoute(fullPath string, rt *Route) {
+	r.checkPending()
+	rt.snapshotConstraints()
+	if _, err := r.tree.addRoute(fullPath); err != nil {
+		panic(err)
+	}
+	if r.routes[fullPath] == nil {
+		r.routes[fullPath] = make(map[string][]*Route)
+	}
+	r.routes[fullPath][rt.method] = append(r.routes[fullPath][rt.method], rt)
+	r.routeList = append(r.routeList, rt)
+	r.pending = rt
+}
+
+// 检查最后注册的路由是否重复
+func (r *router) checkPending() {
+	if rt := r.pending; rt != nil {
+		r.pending = nil
+		r.checkDuplicate(rt)
+	}
+}
+
+// Host和请求头限制都相同的同一路由注册多次时panic
+func (r *router) checkDuplicate(rt *Route) {
+	for _, exist := range r.routes[rt.fullPath][rt.method] {
+		if exist != rt && exist.constraintKey() == rt.constraintKey() {
+			panic(errors.New(fmt.Sprintf("route [%s %s] is already registered", rt.method, rt.fullPath)))
+		}
+	}
+}
+
+// 拼接路由前缀和路由(joinPaths("/api", "v1") 返回 /api/v1)
+func joinPaths(prefix, name string) string {
+	if name == "" {
+		return prefix
+	}
+	if name[0] != '/' {
+		name = "/" + name
+	}
+	return strings.TrimSuffix(prefix, "/") + name
+}
+
 type routerGroup struct {
-	groupName          string
-	handlerMap         map[string]map[string]HandlerFunc
-	handlerMethodMap   map[string][]string
-	treeNode           *treeNode
-	middlewaresFuncMap map[string]map[string][]MiddlewareFunc
-	middlewares        []MiddlewareFunc
+	groupName        string
+	prefix           string       // 路由组的完整前缀(嵌套路由组包含所有父路由组的前缀，如/api/v1)
+	parent           *routerGroup // 父路由组，顶层路由组为nil
+	router           *router
+	handlerMethodMap map[string][]string
+	middlewares      []middleware
+	host             *hostPattern       // Host限制
+	headers          []headerConstraint // 请求头限制
+}
+
+// Group 在路由组下创建嵌套的子路由组(如在/api下创建v1，子路由组的路由以/api/v1为前缀)，子路由组继承父路由组的中间件
+func (r *routerGroup) Group(name string) *routerGroup {
+	return r.router.newGroup(r, name)
 }
 
-// Handle method的有效性校验
-func (r *routerGroup) Handle(name string, method string, handlerFunc HandlerFunc) {
-	r.handle(name, method, handlerFunc)
+// Handle 注册指定method的路由，handlers中最后一个为路由处理函数，之前的均为该路由的中间件(中间件内调用ctx.Next()执行后续处理函数)
+func (r *routerGroup) Handle(name string, method string, handlers ...HandlerFunc) *Route {
+	if len(handlers) == 0 {
+		panic(errors.New(fmt.Sprintf("route [%s %s] must have a handler", method, name)))
+	}
+	last := len(handlers) - 1
+	return r.addRoute(name, method, handlers[last], handlerMiddlewares(handlers[:last]))
 }
 
 // Any 任意类型的路由
-func (r *routerGroup) Any(name string, handleFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, MethodAny, handleFunc, middlewareFunc...)
+func (r *routerGroup) Any(name string, handleFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) *Route {
+	return r.handle(name, MethodAny, handleFunc, middlewareFunc...)
 }
 
 // Get Get类型路由
-func (r *routerGroup) Get(name string, handleFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodGet, handleFunc, middlewareFunc...)
+func (r *routerGroup) Get(name string, handleFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) *Route {
+	return r.handle(name, http.MethodGet, handleFunc, middlewareFunc...)
 }
 
 // Head Head类型路由
-func (r *routerGroup) Head(name string, handleFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodHead, handleFunc, middlewareFunc...)
+func (r *routerGroup) Head(name string, handleFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) *Route {
+	return r.handle(name, http.MethodHead, handleFunc, middlewareFunc...)
 }
 
 // Post Post类型路由
-func (r *routerGroup) Post(name string, handleFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodPost, handleFunc, middlewareFunc...)
+func (r *routerGroup) Post(name string, handleFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) *Route {
+	return r.handle(name, http.MethodPost, handleFunc, middlewareFunc...)
 }
 
 // Put Put类型路由
-func (r *routerGroup) Put(name string, handleFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodPut, handleFunc, middlewareFunc...)
+func (r *routerGroup) Put(name string, handleFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) *Route {
+	return r.handle(name, http.MethodPut, handleFunc, middlewareFunc...)
 }
 
 // Patch Patch类型路由
-func (r *routerGroup) Patch(name string, handleFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodPatch, handleFunc, middlewareFunc...)
+func (r *routerGroup) Patch(name string, handleFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) *Route {
+	return r.handle(name, http.MethodPatch, handleFunc, middlewareFunc...)
 }
 
 // Delete Delete类型路由
-func (r *routerGroup) Delete(name string, handleFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodDelete, handleFunc, middlewareFunc...)
+func (r *routerGroup) Delete(name string, handleFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) *Route {
+	return r.handle(name, http.MethodDelete, handleFunc, middlewareFunc...)
 }
 
 // Connect Connect类型路由
-func (r *routerGroup) Connect(name string, handleFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodConnect, handleFunc, middlewareFunc...)
+func (r *routerGroup) Connect(name string, handleFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) *Route {
+	return r.handle(name, http.MethodConnect, handleFunc, middlewareFunc...)
 }
 
 // Options Options类型路由
-func (r *routerGroup) Options(name string, handleFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodOptions, handleFunc, middlewareFunc...)
+func (r *routerGroup) Options(name string, handleFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) *Route {
+	return r.handle(name, http.MethodOptions, handleFunc, middlewareFunc...)
 }
 
 // Trace Trace类型路由
-func (r *routerGroup) Trace(name string, handleFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodTrace, handleFunc, middlewareFunc...)
+func (r *routerGroup) Trace(name string, handleFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) *Route {
+	return r.handle(name, http.MethodTrace, handleFunc, middlewareFunc...)
 }
 
 // 统一处理
-func (r *routerGroup) handle(name string, method string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	_, ok := r.handlerMap[name]
-	if !ok {
-		r.handlerMap[name] = make(map[string]HandlerFunc)
-		r.middlewaresFuncMap[name] = make(map[string][]MiddlewareFunc)
+func (r *routerGroup) handle(name string, method string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) *Route {
+	return r.addRoute(name, method, handlerFunc, wrapMiddlewares(middlewareFunc))
+}
+
+// 添加路由(路由有歧义或者重复注册时直接panic，在启动阶段就暴露问题)
+func (r *routerGroup) addRoute(name string, method string, handlerFunc HandlerFunc, middlewares []middleware) *Route {
+	fullPath := joinPaths(r.prefix, name)
+	if fullPath == "" {
+		fullPath = "/"
 	}
-	r.handlerMap[name][method] = handlerFunc
+	rt := &Route{group: r, name: name, method: method, fullPath: fullPath, handler: nameOfFunction(handlerFunc),
+		handlerFunc: handlerFunc, middlewares: middlewares}
+	r.router.addRoute(fullPath, rt)
 	r.handlerMethodMap[method] = append(r.handlerMethodMap[method], name)
-	methodMap := make(map[string]HandlerFunc)
-	methodMap[method] = handlerFunc
-	r.middlewaresFuncMap[name][method] = append(r.middlewaresFuncMap[name][method], middlewareFunc...) // 添加中间件
-	r.treeNode.Put(name)
-}
-
-// 路由实现引入中间件
-func (r *routerGroup) methodHandle(ctx *Context, name string, method string, handler HandlerFunc) {
-	// 路由组级中间件
-	if r.middlewares != nil {
-		for _, middlewareFunc := range r.middlewares {
-			handler = middlewareFunc(handler)
-		}
-	}
-	// 路由级中间件
-	middlewareFuncs := r.middlewaresFuncMap[name][method]
-	if middlewareFuncs != nil {
-		for _, middlewareFunc := range middlewareFuncs {
-			handler = middlewareFunc(handler)
-		}
+	return rt
+}
+
+// 路由实现引入中间件：全局、父路由组、路由组和路由级中间件按orderMiddlewares的顺序组成处理链，最后是路由处理函数
+func (r *routerGroup) methodHandle(ctx *Context, rt *Route) {
+	ctx.handlers = appendMiddlewares(ctx.handlers[:0], rt.allMiddlewares())
+	ctx.handlers = append(ctx.handlers, rt.handlerFunc)
+	ctx.Next()
+}
+
+// 路由的所有中间件(按注册层级：全局 -> 父路由组 -> 路由组 -> 路由级)
+func (rt *Route) allMiddlewares() []middleware {
+	return append(rt.group.combineMiddlewares(nil), rt.middlewares...)
+}
+
+// 组合路由组及其所有父路由组的中间件(父路由组的在前)
+func (r *routerGroup) combineMiddlewares(middlewares []middleware) []middleware {
+	if r.parent != nil {
+		middlewares = r.parent.combineMiddlewares(middlewares)
 	}
-	// 执行路由逻辑
-	handler(ctx)
+	return append(middlewares, r.middlewares...)
 }
 
-// Use 注册中间件
+// Use 注册MiddlewareFunc风格的中间件，嵌套顺序与旧版本一致：后注册的中间件在外层，路由级中间件在路由组中间件外层，
+// 并且都在UseHandler注册的Next风格中间件外层(先执行)
 func (r *routerGroup) Use(middlewareFunc ...MiddlewareFunc) {
-	r.middlewares = append(r.middlewares, middlewareFunc...)
+	r.middlewares = append(r.middlewares, wrapMiddlewares(middlewareFunc)...)
+}
+
+// UseHandler 注册Next风格的中间件(中间件内调用ctx.Next()执行后续处理函数，调用ctx.Abort()终止后续处理函数)，
+// 按注册顺序执行：全局 -> 父路由组 -> 路由组 -> 路由级(Handle中的中间件)
+func (r *routerGroup) UseHandler(handlers ...HandlerFunc) {
+	r.middlewares = append(r.middlewares, handlerMiddlewares(handlers)...)
 }
 
 type ErrorHandler func(err error) (int, any)
 
+// 所有标准的请求方式(ANY路由支持的请求方式)
+var anyMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+}
+
 type Engine struct {
 	router
-	funcMap          template.FuncMap
-	HTMLRender       render.HTMLRender
-	pool             sync.Pool
-	Logger           *qlog.Logger
-	middles          []MiddlewareFunc
-	errorHandler     ErrorHandler
-	OpenGateway      bool
-	gatewayConfigs   []gateway.GWConfig
-	gatewayTreeNode  *gateway.TreeNode
-	gatewayConfigMap map[string]gateway.GWConfig
-	RegisterType     string
-	RegisterOption   register.Option
-	RegisterCli      register.QueenRegister
+	funcMap            template.FuncMap
+	HTMLRender         render.HTMLRender
+	pool               sync.Pool
+	Logger             *qlog.Logger
+	middles            []middleware
+	noRoute            []HandlerFunc
+	noMethod           []HandlerFunc
+	DisableAutoHead    bool // 关闭HEAD请求自动由GET路由处理
+	DisableAutoOptions bool // 关闭OPTIONS请求自动返回路由支持的请求方式
+	Debug              bool // 调试模式：启动时打印路由表
+	errorHandler       ErrorHandler
+	OpenGateway        bool
+	gatewayConfigs     []gateway.GWConfig
+	gatewayTreeNode    *gateway.TreeNode
+	gatewayConfigMap   map[string]gateway.GWConfig
+	RegisterType       string
+	RegisterOption     register.Option
+	RegisterCli        register.QueenRegister
+	Server             ServerOptions       // http.Server的配置(超时时间、请求头大小、ErrorLog等)
+	ShutdownTimeout    time.Duration       // 优雅关闭时等待处理中请求完成的最长时间(默认10s)
+	Upgrader           *websocket.Upgrader // WebSocket握手配置(为nil时使用默认配置)
+	ETag               ETagMode            // 渲染输出的ETag生成方式(默认不生成，可以通过中间件ETag按路由组设置)
+	trustedCIDRs       []*net.IPNet        // 可信代理(见SetTrustedProxies)
+	MaxBodyBytes       int64               // 请求体大小限制(默认不限制，超过时返回413，可以通过中间件MaxBodyBytes按路由覆盖)
+	MaxMultipartMemory int64               // 解析multipart表单时保存在内存中的最大字节数，超过部分写入临时文件(默认32M)
+	onStart            []HookFunc
+	onStop             []HookFunc
+	servers            []*http.Server
+	serverLock         sync.Mutex
+	ownRegisterCli     bool // RegisterCli是否由Engine创建(由Engine创建的在关闭时自动Close)
+	shutdownOnce       sync.Once
+	shutdownErr        error
+	done               chan struct{} // Shutdown完成后关闭
 }
 
 func New() *Engine {
 	engine := &Engine{
-		router:           router{},
+		router: router{
+			tree:        &radixNode{},
+			routes:      make(map[string]map[string][]*Route),
+			namedRoutes: make(map[string]*Route),
+		},
 		gatewayTreeNode:  &gateway.TreeNode{Name: "/", Children: make([]*gateway.TreeNode, 0)},
 		gatewayConfigMap: make(map[string]gateway.GWConfig),
+		done:             make(chan struct{}),
 	}
+	engine.router.engine = engine
+	engine.funcMap = template.FuncMap{"url": engine.URL}
 	engine.pool.New = func() any {
 		return engine.allocateContext()
 	}
@@ -184,17 +395,32 @@ func New() *Engine {
 func Default() *Engine {
 	engine := New()
 	engine.Logger = qlog.Default()
-	logPath, ok := config.Conf.Log["path"]
+	logPath, ok := config.Load().Log["path"]
 	if ok {
 		engine.Logger.SetLogPath(logPath.(string))
 	}
 	engine.Use(Logging, Recovery)
-	engine.router.engine = engine
 	return engine
 }
 
+// Use 注册全局中间件(对之后创建的路由组生效)，嵌套顺序见routerGroup.Use
 func (e *Engine) Use(middles ...MiddlewareFunc) {
-	e.middles = append(e.middles, middles...)
+	e.middles = append(e.middles, wrapMiddlewares(middles)...)
+}
+
+// UseHandler 注册Next风格的全局中间件(对之后创建的路由组生效)
+func (e *Engine) UseHandler(handlers ...HandlerFunc) {
+	e.middles = append(e.middles, handlerMiddlewares(handlers)...)
+}
+
+// NoRoute 设置路由匹配失败(404)时的处理函数，处理前会先经过全局中间件
+func (e *Engine) NoRoute(handlers ...HandlerFunc) {
+	e.noRoute = handlers
+}
+
+// NoMethod 设置路由匹配成功但请求方式不匹配(405)时的处理函数，处理前会先经过全局中间件(响应头Allow中为该路由支持的请求方式)
+func (e *Engine) NoMethod(handlers ...HandlerFunc) {
+	e.noMethod = handlers
 }
 
 // 仿照gin框架源码作的处理
@@ -204,10 +430,10 @@ func (e *Engine) allocateContext() any {
 
 func (e *Engine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx := e.pool.Get().(*Context)
-	ctx.W = w
-	ctx.R = r
+	ctx.reset(w, r)
 	ctx.Logger = e.Logger
 	e.httpRequestHandle(ctx, w, r)
+	ctx.checkBodyTooLarge()
 	e.pool.Put(ctx)
 }
 
@@ -222,6 +448,13 @@ func (e *Engine) httpRequestHandle(ctx *Context, w http.ResponseWriter, r *http.
 			return
 		}
 		gwConfig := e.gatewayConfigMap[node.GwName]
+		// 沿用或生成请求ID，转发给下游服务并返回给客户端
+		requestID := r.Header.Get(requestid.Header)
+		if !validRequestID(requestID) {
+			requestID = requestid.New()
+			r.Header.Set(requestid.Header, requestID)
+		}
+		ctx.requestID = requestID
 		gwConfig.Header(ctx.R)
 		addr, err := e.RegisterCli.GetValue(gwConfig.ServiceName)
 		if err != nil {
@@ -247,6 +480,7 @@ func (e *Engine) httpRequestHandle(ctx *Context, w http.ResponseWriter, r *http.
 		}
 		response := func(response *http.Response) error {
 			log.Println("响应修改")
+			response.Header.Set(requestid.Header, requestID)
 			return nil
 		}
 		handler := func(writer http.ResponseWriter, request *http.Request, err error) {
@@ -258,36 +492,116 @@ func (e *Engine) httpRequestHandle(ctx *Context, w http.ResponseWriter, r *http.
 		return
 	}
 	// 不开启网关的处理
-	method := r.Method
-	for _, group := range e.router.groups {
-		routerName := utils.SubStringLast(r.URL.Path, utils.ConcatenatedString([]string{"/", group.groupName}))
-		node := group.treeNode.Get(routerName)
-		if node != nil && node.isEnd { // 路由匹配成功
-			// ANY下的匹配
-			handler, ok := group.handlerMap[node.routerName][MethodAny]
-			if ok {
-				group.methodHandle(ctx, node.routerName, MethodAny, handler)
-				return
-			}
-			// 指定Method的匹配（如Get，Post）
-			handler, ok = group.handlerMap[node.routerName][method]
-			if ok {
-				group.methodHandle(ctx, node.routerName, method, handler)
-				return
+	node := e.tree.find(r.URL.Path, &ctx.params, e.acceptNode(ctx))
+	if node == nil { // 路由匹配失败，404 NotFound
+		e.handleWithMiddles(ctx, e.noRoute, notFoundHandler)
+		return
+	}
+	methods := e.routes[node.fullPath]
+	if rt, ok := e.matchMethod(ctx, methods, r.Method); ok {
+		rt.group.methodHandle(ctx, rt)
+		return
+	}
+	allow := e.allowedMethods(ctx, methods)
+	if allow == "" { // 该路由的所有请求方式都不满足Host和请求头限制，404 NotFound
+		e.handleWithMiddles(ctx, e.noRoute, notFoundHandler)
+		return
+	}
+	w.Header().Set("Allow", allow)
+	if r.Method == http.MethodOptions && !e.DisableAutoOptions {
+		e.handleWithMiddles(ctx, nil, optionsHandler)
+		return
+	}
+	// url匹配但请求方式不匹配，405 MethodNotAllowed
+	e.handleWithMiddles(ctx, e.noMethod, methodNotAllowedHandler)
+}
+
+// 路由终点上是否有满足Host和请求头限制的路由(任意请求方式)，没有时继续尝试其他路由
+func (e *Engine) acceptNode(ctx *Context) func(node *radixNode) bool {
+	return func(node *radixNode) bool {
+		n := len(ctx.params)
+		defer func() { ctx.params = ctx.params[:n] }()
+		for _, routes := range e.routes[node.fullPath] {
+			if _, ok := matchConstraints(ctx, routes); ok {
+				return true
 			}
-			// url匹配但请求方式不匹配，405 MethodNotAllowed
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			fmt.Fprintln(w, utils.ConcatenatedString([]string{method, " not allowed"}))
-			return
 		}
+		return false
+	}
+}
+
+// 根据请求方式找到路由：先匹配指定Method（如Get，Post），HEAD请求可以由GET路由处理，最后匹配ANY
+func (e *Engine) matchMethod(ctx *Context, methods map[string][]*Route, method string) (*Route, bool) {
+	if rt, ok := matchConstraints(ctx, methods[method]); ok {
+		return rt, true
+	}
+	if method == http.MethodHead && !e.DisableAutoHead {
+		if rt, ok := matchConstraints(ctx, methods[http.MethodGet]); ok {
+			return rt, true
+		}
+	}
+	return matchConstraints(ctx, methods[MethodAny])
+}
+
+// 路由支持的请求方式(用于响应头Allow，只包括满足Host和请求头限制的路由)，没有满足限制的路由时返回空字符串
+func (e *Engine) allowedMethods(ctx *Context, methods map[string][]*Route) string {
+	matched := make(map[string]bool, len(methods))
+	n := len(ctx.params)
+	for method, routes := range methods {
+		if _, ok := matchConstraints(ctx, routes); ok {
+			matched[method] = true
+		}
+		ctx.params = ctx.params[:n]
 	}
-	// 路由匹配失败，404 NotFound
-	w.WriteHeader(http.StatusNotFound)
-	fmt.Fprintln(w, r.RequestURI+" not found")
+	if len(matched) == 0 {
+		return ""
+	}
+	if matched[MethodAny] {
+		return strings.Join(anyMethods, ", ")
+	}
+	allow := make([]string, 0, len(matched)+2)
+	for method := range matched {
+		allow = append(allow, method)
+	}
+	if matched[http.MethodGet] && !matched[http.MethodHead] && !e.DisableAutoHead {
+		allow = append(allow, http.MethodHead)
+	}
+	if !matched[http.MethodOptions] && !e.DisableAutoOptions {
+		allow = append(allow, http.MethodOptions)
+	}
+	sort.Strings(allow)
+	return strings.Join(allow, ", ")
+}
+
+// 经过全局中间件后执行handlers，handlers为空时执行defaultHandler
+func (e *Engine) handleWithMiddles(ctx *Context, handlers []HandlerFunc, defaultHandler HandlerFunc) {
+	ctx.handlers = appendMiddlewares(ctx.handlers[:0], e.middles)
+	if len(handlers) > 0 {
+		ctx.handlers = append(ctx.handlers, handlers...)
+	} else {
+		ctx.handlers = append(ctx.handlers, defaultHandler)
+	}
+	ctx.Next()
 }
 
+func notFoundHandler(ctx *Context) {
+	ctx.String(http.StatusNotFound, "%s not found\n", ctx.R.RequestURI)
+}
+
+func methodNotAllowedHandler(ctx *Context) {
+	ctx.String(http.StatusMethodNotAllowed, "%s not allowed\n", ctx.R.Method)
+}
+
+func optionsHandler(ctx *Context) {
+	ctx.W.WriteHeader(http.StatusNoContent)
+}
+
+// SetFuncMap 设置模板函数(未设置url时保留内置的url函数，模板中通过{{url "user.info" "id" 1}}生成路由对应的url)
 func (e *Engine) SetFuncMap(funcMap template.FuncMap) {
-	e.funcMap = funcMap
+	e.funcMap = template.FuncMap{"url": e.URL}
+	for name, fn := range funcMap {
+		e.funcMap[name] = fn
+	}
 }
 
 func (e *Engine) SetGatewayConfig(configs []gateway.GWConfig) {
@@ -307,7 +621,7 @@ func (e *Engine) LoadTemplate(pattern string) {
 
 // LoadTemplateConf 根据配置文件读取模板
 func (e *Engine) LoadTemplateConf() {
-	pattern, ok := config.Conf.Template["pattern"]
+	pattern, ok := config.Load().Template["pattern"]
 	if ok {
 		t := template.Must(template.New("").Funcs(e.funcMap).ParseGlob(pattern.(string)))
 		e.SetHtmlTemplate(t)
@@ -323,41 +637,3 @@ func (e *Engine) SetHtmlTemplate(t *template.Template) {
 func (e *Engine) RegisterErrorHandler(handler ErrorHandler) {
 	e.errorHandler = handler
 }
-
-func (e *Engine) Run(addr string) {
-	if e.RegisterType == "nacos" {
-		r := &register.QueenNacosRegister{}
-		err := r.CreateCli(e.RegisterOption)
-		if err != nil {
-			panic(err)
-		}
-		e.RegisterCli = r
-	}
-	if e.RegisterType == "etcd" {
-		r := &register.QueenEtcdRegister{}
-		err := r.CreateCli(e.RegisterOption)
-		if err != nil {
-			panic(err)
-		}
-		e.RegisterCli = r
-	}
-
-	http.Handle("/", e)
-	err := http.ListenAndServe(addr, nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-}
-
-// RunTLS 支持https
-func (e *Engine) RunTLS(addr, certFile, keyFile string) {
-	err := http.ListenAndServeTLS(addr, certFile, keyFile, e.Handler())
-	if err != nil {
-		log.Fatal(err)
-	}
-}
-
-// Handler 返回Handler
-func (e *Engine) Handler() http.Handler {
-	return e
-}
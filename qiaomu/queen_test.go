@@ -0,0 +1,539 @@
+package qiaomu
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/qingbo1011/qiaomu/websocket"
+)
+
+func performRequest(e *Engine, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddlewareChain(t *testing.T) {
+	var trace []string
+	engine := New()
+	engine.Use(func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			trace = append(trace, "engine:before")
+			next(ctx)
+			trace = append(trace, "engine:after")
+		}
+	})
+	group := engine.Group("user")
+	group.UseHandler(func(ctx *Context) {
+		trace = append(trace, "group:before")
+		ctx.Next()
+		trace = append(trace, "group:after")
+	})
+	group.Handle("/info", http.MethodGet, func(ctx *Context) {
+		trace = append(trace, "route")
+	}, func(ctx *Context) {
+		trace = append(trace, "handler")
+	})
+
+	performRequest(engine, http.MethodGet, "/user/info")
+	want := "engine:before,group:before,route,handler,group:after,engine:after"
+	if got := strings.Join(trace, ","); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var trace []string
+	middleware := func(name string) MiddlewareFunc {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(ctx *Context) {
+				trace = append(trace, name)
+				next(ctx)
+			}
+		}
+	}
+	handler := func(name string) HandlerFunc {
+		return func(ctx *Context) {
+			trace = append(trace, name)
+			ctx.Next()
+		}
+	}
+	engine := New()
+	engine.Use(middleware("engine1"), middleware("engine2"))
+	engine.UseHandler(handler("engineHandler"))
+	group := engine.Group("user")
+	group.UseHandler(handler("groupHandler"))
+	group.Use(middleware("group1"), middleware("group2"))
+	group.Get("/info", func(ctx *Context) {
+		trace = append(trace, "handler")
+	}, middleware("route1"), middleware("route2"))
+
+	performRequest(engine, http.MethodGet, "/user/info")
+	// MiddlewareFunc保持旧版本的嵌套顺序(后注册的在外层，路由级在路由组外层)，Next风格的中间件在内层按注册顺序执行
+	want := "route2,route1,group2,group1,engine2,engine1,engineHandler,groupHandler,handler"
+	if got := strings.Join(trace, ","); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestMiddlewareAbort(t *testing.T) {
+	var trace []string
+	engine := New()
+	group := engine.Group("user")
+	group.Use(func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			next(ctx)
+			trace = append(trace, "outer:after")
+		}
+	})
+	group.UseHandler(func(ctx *Context) {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, map[string]string{"msg": "unauthorized"})
+	})
+	group.Get("/info", func(ctx *Context) {
+		trace = append(trace, "handler")
+	})
+
+	w := performRequest(engine, http.MethodGet, "/user/info")
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if got := strings.Join(trace, ","); got != "outer:after" {
+		t.Errorf("got %s, want outer:after", got)
+	}
+}
+
+func TestMiddlewareFuncWithoutNext(t *testing.T) {
+	called := false
+	engine := New()
+	group := engine.Group("user")
+	group.Get("/basic", func(ctx *Context) {
+		called = true
+	}, (&Accounts{Users: map[string]string{"qingbo": "1234"}}).BasicAuth)
+
+	w := performRequest(engine, http.MethodGet, "/user/basic")
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if called {
+		t.Error("handler should not be called after BasicAuth failed")
+	}
+}
+
+func TestRouteParams(t *testing.T) {
+	engine := New()
+	group := engine.Group("user")
+	group.Get("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "info")
+	})
+	group.Get("/:id", func(ctx *Context) {
+		id, err := ctx.ParamInt("id")
+		if err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.String(http.StatusOK, "id=%d", id)
+	})
+
+	var testcases = []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/user/info", http.StatusOK, "info"},
+		{"/user/10", http.StatusOK, "id=10"},
+		{"/user/abc", http.StatusBadRequest, ""},
+	}
+	for _, testcase := range testcases {
+		w := performRequest(engine, http.MethodGet, testcase.path)
+		if w.Code != testcase.code {
+			t.Errorf("%s: got status %d, want %d", testcase.path, w.Code, testcase.code)
+		}
+		if testcase.body != "" && w.Body.String() != testcase.body {
+			t.Errorf("%s: got body %q, want %q", testcase.path, w.Body.String(), testcase.body)
+		}
+	}
+}
+
+func TestNestedGroup(t *testing.T) {
+	var trace []string
+	engine := New()
+	api := engine.Group("api")
+	api.UseHandler(func(ctx *Context) {
+		trace = append(trace, "api")
+	})
+	v1 := api.Group("/v1/")
+	v1.UseHandler(func(ctx *Context) {
+		trace = append(trace, "v1")
+	})
+	v1.Group("user").Get("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "/api/v1/user/info")
+	})
+	engine.Group("user").Get("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "/user/info")
+	})
+
+	for _, path := range []string{"/api/v1/user/info", "/user/info"} {
+		w := performRequest(engine, http.MethodGet, path)
+		if w.Body.String() != path {
+			t.Errorf("%s: got body %q", path, w.Body.String())
+		}
+	}
+	if got := strings.Join(trace, ","); got != "api,v1" {
+		t.Errorf("got %s, want api,v1", got)
+	}
+	if w := performRequest(engine, http.MethodGet, "/api/user/info"); w.Code != http.StatusNotFound {
+		t.Errorf("/api/user/info: got status %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestNoRouteNoMethod(t *testing.T) {
+	var trace []string
+	engine := New()
+	engine.UseHandler(func(ctx *Context) {
+		trace = append(trace, "global")
+	})
+	engine.NoRoute(func(ctx *Context) {
+		ctx.JSON(http.StatusNotFound, map[string]string{"msg": "no route"})
+	})
+	group := engine.Group("user")
+	group.Get("/info", func(ctx *Context) {})
+	group.Post("/info", func(ctx *Context) {})
+
+	w := performRequest(engine, http.MethodGet, "/order/info")
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "no route") {
+		t.Errorf("no route: got %d %q", w.Code, w.Body.String())
+	}
+	w = performRequest(engine, http.MethodDelete, "/user/info")
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("no method: got status %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS, POST" {
+		t.Errorf("no method: got Allow %q", allow)
+	}
+	if got := strings.Join(trace, ","); got != "global,global" {
+		t.Errorf("got %s, want global,global", got)
+	}
+}
+
+func TestAutoHeadOptions(t *testing.T) {
+	engine := New()
+	group := engine.Group("user")
+	group.Get("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "info")
+	})
+
+	if w := performRequest(engine, http.MethodHead, "/user/info"); w.Code != http.StatusOK {
+		t.Errorf("HEAD: got status %d, want %d", w.Code, http.StatusOK)
+	}
+	w := performRequest(engine, http.MethodOptions, "/user/info")
+	if w.Code != http.StatusNoContent || w.Header().Get("Allow") != "GET, HEAD, OPTIONS" {
+		t.Errorf("OPTIONS: got %d, Allow %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	engine.DisableAutoHead = true
+	engine.DisableAutoOptions = true
+	if w := performRequest(engine, http.MethodHead, "/user/info"); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("HEAD disabled: got status %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+	if w := performRequest(engine, http.MethodOptions, "/user/info"); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("OPTIONS disabled: got status %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestNamedRouteURL(t *testing.T) {
+	engine := New()
+	group := engine.Group("user")
+	group.Get("/:id/orders", func(ctx *Context) {}).Name("user.orders")
+	group.Get("/files/**", func(ctx *Context) {}).Name("user.files")
+
+	var testcases = []struct {
+		name   string
+		params []any
+		url    string
+		err    bool
+	}{
+		{"user.orders", []any{"id", 1}, "/user/1/orders", false},
+		{"user.orders", []any{"id", "a b", "page", 2, "tag", []string{"x", "y"}}, "/user/a%20b/orders?page=2&tag=x&tag=y", false},
+		{"user.files", []any{"**", "docs/a.txt"}, "/user/files/docs/a.txt", false},
+		{"user.orders", nil, "", true},
+		{"user.orders", []any{"id"}, "", true},
+		{"order.info", nil, "", true},
+	}
+	for _, testcase := range testcases {
+		u, err := engine.URL(testcase.name, testcase.params...)
+		if (err != nil) != testcase.err || u != testcase.url {
+			t.Errorf("%s %v: got %q %v, want %q", testcase.name, testcase.params, u, err, testcase.url)
+		}
+	}
+
+	engine.SetFuncMap(nil)
+	engine.LoadTemplate("testdata/url.tmpl")
+	group.Get("/link", func(ctx *Context) {
+		ctx.Template("url.tmpl", nil)
+	})
+	if w := performRequest(engine, http.MethodGet, "/user/link"); !strings.Contains(w.Body.String(), `href="/user/7/orders"`) {
+		t.Errorf("template url: got %q", w.Body.String())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("duplicate route name should panic")
+		}
+	}()
+	group.Get("/:id", func(ctx *Context) {}).Name("user.orders")
+}
+
+func TestRoutes(t *testing.T) {
+	engine := New()
+	engine.Use(Recovery)
+	group := engine.Group("user")
+	group.Use(Limiter(10, 10))
+	group.Get("/:id", func(ctx *Context) {}, Logging).Name("user.info")
+	engine.DebugRoutes()
+
+	routes := engine.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("got %d routes, want 2", len(routes))
+	}
+	rt := routes[0]
+	if rt.Method != http.MethodGet || rt.Path != "/user/:id" || rt.Name != "user.info" || !strings.Contains(rt.Handler, "TestRoutes") {
+		t.Errorf("got route %+v", rt)
+	}
+	if len(rt.Middlewares) != 3 || !strings.HasSuffix(rt.Middlewares[0], "qiaomu.Logging") || !strings.HasSuffix(rt.Middlewares[2], "qiaomu.Recovery") {
+		t.Errorf("got middlewares %v", rt.Middlewares)
+	}
+
+	w := performRequest(engine, http.MethodGet, "/debug/routes")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"path":"/user/:id"`) {
+		t.Errorf("/debug/routes: got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestHostAndHeaderRouting(t *testing.T) {
+	engine := New()
+	engine.Host("*.example.com").Get("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "tenant=%s", ctx.Param("subdomain"))
+	})
+	engine.Host("api.example.org").Get("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "api")
+	})
+	engine.Group("").Get("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "default")
+	})
+	v2 := engine.Group("user").Header("Accept-Version", "v2")
+	v2.Get("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "v2")
+	})
+	engine.Group("user").Get("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "v1")
+	})
+	engine.Group("order").Get("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "acme")
+	}).Header("X-Tenant", "acme")
+
+	var testcases = []struct {
+		host    string
+		path    string
+		headers map[string]string
+		code    int
+		body    string
+	}{
+		{"acme.example.com:8080", "/info", nil, http.StatusOK, "tenant=acme"},
+		{"a.b.example.com", "/info", nil, http.StatusOK, "tenant=a.b"},
+		{"API.example.org", "/info", nil, http.StatusOK, "api"},
+		{"example.com", "/info", nil, http.StatusOK, "default"},
+		{"localhost", "/user/info", map[string]string{"Accept-Version": "v2"}, http.StatusOK, "v2"},
+		{"localhost", "/user/info", nil, http.StatusOK, "v1"},
+		{"localhost", "/order/info", map[string]string{"X-Tenant": "acme"}, http.StatusOK, "acme"},
+		{"localhost", "/order/info", map[string]string{"X-Tenant": "other"}, http.StatusNotFound, ""},
+	}
+	for _, testcase := range testcases {
+		req := httptest.NewRequest(http.MethodGet, testcase.path, nil)
+		req.Host = testcase.host
+		for key, value := range testcase.headers {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != testcase.code || (testcase.body != "" && w.Body.String() != testcase.body) {
+			t.Errorf("%s%s %v: got %d %q, want %d %q", testcase.host, testcase.path, testcase.headers, w.Code, w.Body.String(), testcase.code, testcase.body)
+		}
+	}
+}
+
+func TestHostConstraintBacktracking(t *testing.T) {
+	engine := New()
+	engine.Host("a.example.com").Get("/docs/:page", func(ctx *Context) {
+		ctx.String(http.StatusOK, "a:%s", ctx.Param("page"))
+	})
+	engine.Host("b.example.com").Get("/docs/index", func(ctx *Context) {
+		ctx.String(http.StatusOK, "b:index")
+	})
+	// 同一路由上满足限制的有多个时选最具体的，而不是先注册的
+	engine.Host("*.example.org").Get("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "wildcard")
+	})
+	engine.Host("api.example.org").Get("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "api")
+	})
+
+	var testcases = []struct {
+		host string
+		path string
+		code int
+		body string
+	}{
+		{"a.example.com", "/docs/index", http.StatusOK, "a:index"},
+		{"b.example.com", "/docs/index", http.StatusOK, "b:index"},
+		{"b.example.com", "/docs/other", http.StatusNotFound, ""},
+		{"api.example.org", "/info", http.StatusOK, "api"},
+		{"www.example.org", "/info", http.StatusOK, "wildcard"},
+	}
+	for _, testcase := range testcases {
+		req := httptest.NewRequest(http.MethodGet, testcase.path, nil)
+		req.Host = testcase.host
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != testcase.code || (testcase.body != "" && w.Body.String() != testcase.body) {
+			t.Errorf("%s%s: got %d %q, want %d %q", testcase.host, testcase.path, w.Code, w.Body.String(), testcase.code, testcase.body)
+		}
+	}
+}
+
+func TestRouteHeaderVariants(t *testing.T) {
+	engine := New()
+	api := engine.Group("api")
+	api.Get("/v", func(ctx *Context) {
+		ctx.String(http.StatusOK, "default")
+	})
+	api.Get("/v", func(ctx *Context) {
+		ctx.String(http.StatusOK, "v1")
+	}).Header("Accept-Version", "v1")
+	api.Get("/v", func(ctx *Context) {
+		ctx.String(http.StatusOK, "v2")
+	}).Header("Accept-Version", "v2")
+	// 路由组的限制只对之后注册的路由生效
+	api.Header("X-Tenant", "acme")
+	api.Get("/v", func(ctx *Context) {
+		ctx.String(http.StatusOK, "acme")
+	}).Header("Accept-Version", "v2")
+
+	for version, want := range map[string]string{"v1": "v1", "v2": "v2", "": "default"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v", nil)
+		if version != "" {
+			req.Header.Set("Accept-Version", version)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Body.String() != want {
+			t.Errorf("version %q: got %d %q, want %q", version, w.Code, w.Body.String(), want)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("duplicate route: want panic")
+		}
+	}()
+	engine.Group("api").Get("/v", func(ctx *Context) {}).Header("Accept-Version", "v2")
+	engine.Handler()
+}
+
+func TestParamConstraintRoute(t *testing.T) {
+	engine := New()
+	engine.Group("user").Get("/getid/:id<int>", func(ctx *Context) {
+		ctx.String(http.StatusOK, "id=%s", ctx.Param("id"))
+	}).Name("user.getid")
+
+	if w := performRequest(engine, http.MethodGet, "/user/getid/12"); w.Body.String() != "id=12" {
+		t.Errorf("got %d %q", w.Code, w.Body.String())
+	}
+	if w := performRequest(engine, http.MethodGet, "/user/getid/abc"); w.Code != http.StatusNotFound {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if constraints := engine.Routes()[0].Constraints; constraints["id"] != "int" {
+		t.Errorf("got constraints %v", constraints)
+	}
+	if _, err := engine.URL("user.getid", "id", "abc"); err == nil {
+		t.Error("URL should check param constraint")
+	}
+}
+
+func TestMount(t *testing.T) {
+	sub := New()
+	sub.Group("user").Get("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "sub:%s", ctx.R.URL.Path)
+	})
+	var trace []string
+	engine := New()
+	admin := engine.Group("admin")
+	admin.UseHandler(func(ctx *Context) {
+		trace = append(trace, ctx.R.URL.Path)
+	})
+	admin.Mount("/sub", sub)
+	admin.Mount("/mux", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("mux:" + r.URL.Path))
+	}))
+	engine.Group("health").Post("", WrapF(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	var testcases = []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodGet, "/admin/sub/user/info", "sub:/user/info"},
+		{http.MethodPost, "/admin/mux/a/b", "mux:/a/b"},
+		{http.MethodGet, "/admin/mux", "mux:/"},
+		{http.MethodPost, "/health", "ok"},
+	}
+	for _, testcase := range testcases {
+		if w := performRequest(engine, testcase.method, testcase.path); w.Body.String() != testcase.body {
+			t.Errorf("%s %s: got %d %q, want %q", testcase.method, testcase.path, w.Code, w.Body.String(), testcase.body)
+		}
+	}
+	if got := strings.Join(trace, ","); got != "/admin/sub/user/info,/admin/mux/a/b,/admin/mux" {
+		t.Errorf("group middleware got %s", got)
+	}
+}
+
+func TestWebSocketRoute(t *testing.T) {
+	engine := New()
+	group := engine.Group("ws")
+	group.UseHandler(func(ctx *Context) {
+		if ctx.GetHeader("Authorization") != "token" {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		ctx.Set("user", "qingbo")
+		ctx.Next()
+	})
+	group.WebSocket("/echo", func(ctx *Context, conn *websocket.Conn) {
+		user, _ := ctx.Get("user")
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte(user.(string)+":"+string(data)))
+	})
+	server := httptest.NewServer(engine)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/echo"
+
+	if _, resp, err := websocket.Dial(url, nil); err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("without token: got %v %v", resp, err)
+	}
+	conn, _, err := websocket.Dial(url, http.Header{"Authorization": {"token"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.WriteMessage(websocket.TextMessage, []byte("hello"))
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != "qingbo:hello" {
+		t.Errorf("got %q %v", data, err)
+	}
+}
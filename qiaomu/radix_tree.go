@@ -0,0 +1,387 @@
+package qiaomu
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Param 路由参数(以/user/get/:id为例，Key为id，Value为请求路径中对应位置的值)
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params 路由匹配过程中提取出的所有路由参数
+type Params []Param
+
+// Get 根据key获取路由参数的值
+func (ps Params) Get(key string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// ByName 根据key获取路由参数的值，不存在时返回空字符串
+func (ps Params) ByName(key string) string {
+	value, _ := ps.Get(key)
+	return value
+}
+
+type nodeType uint8
+
+const (
+	static   nodeType = iota // 静态节点，如/user/info
+	param                    // 参数节点，如:id、:id<int>，匹配一段路径
+	wildcard                 // 通配节点*，匹配一段路径
+	catchAll                 // 通配节点**，匹配剩余的全部路径
+)
+
+// radixNode 压缩前缀树(radix tree)节点
+// 匹配优先级：静态节点 > 有约束的参数节点 > 参数节点 > * > **，高优先级的分支匹配失败时会回溯尝试低优先级的分支
+// 节点的所有字段只在注册路由时写入，处理请求时只读，因此可以被多个请求并发访问
+type radixNode struct {
+	path          string           // 静态节点为压缩后的路径片段；参数节点为:id或:id<int>；通配节点为*或**
+	nType         nodeType         // 节点类型
+	indices       string           // 静态子节点path的首字母，与children一一对应，用于快速定位子节点
+	children      []*radixNode     // 静态子节点(按priority从高到低排序)
+	paramChildren []*radixNode     // 参数子节点(有约束的在前，没有约束的最多一个且在最后)
+	wildChild     *radixNode       // *子节点
+	catchAllChild *radixNode       // **子节点
+	paramName     string           // 参数节点的参数名(如id)
+	constraint    *paramConstraint // 参数节点的约束，为nil时匹配任意值
+	priority      uint32           // 经过该节点的路由数量，数量越多的子节点越先被查找
+	fullPath      string           // 路由终点对应的完整路由(如/user/get/:id)
+	isEnd         bool             // 表示该节点是否是某一个路由的终点
+}
+
+// 参数约束(如:id<int>中的int，:slug<[a-z-]+>中的[a-z-]+)
+type paramConstraint struct {
+	expr  string
+	match func(value string) bool
+}
+
+// 内置的参数约束，其余的约束按正则表达式处理(需要匹配参数的完整值)
+var builtinConstraints = map[string]*regexp.Regexp{
+	"int":   regexp.MustCompile(`^-?[0-9]+$`),
+	"uint":  regexp.MustCompile(`^[0-9]+$`),
+	"alpha": regexp.MustCompile(`^[a-zA-Z]+$`),
+	"uuid":  regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`),
+}
+
+func newParamConstraint(expr string) (*paramConstraint, error) {
+	re, ok := builtinConstraints[expr]
+	if !ok {
+		var err error
+		re, err = regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &paramConstraint{expr: expr, match: re.MatchString}, nil
+}
+
+// 约束表达式(没有约束时为空字符串)
+func (c *paramConstraint) String() string {
+	if c == nil {
+		return ""
+	}
+	return c.expr
+}
+
+// 路由片段
+type routeToken struct {
+	nType      nodeType
+	text       string
+	name       string           // 参数名
+	constraint *paramConstraint // 参数约束
+}
+
+// 解析参数片段(:id 或 :id<int>)
+func parseParam(path, segment string) (routeToken, error) {
+	token := routeToken{nType: param, text: segment, name: segment[1:]}
+	if i := strings.IndexByte(segment, '<'); i != -1 {
+		if segment[len(segment)-1] != '>' || i == len(segment)-2 {
+			return token, errors.New(fmt.Sprintf("param constraint [%s] in path [%s] must be like :id<int>", segment, path))
+		}
+		// 参数值不会包含/，包含/的约束永远无法匹配
+		if strings.IndexByte(segment[i:], '/') != -1 {
+			return token, errors.New(fmt.Sprintf("param constraint [%s] in path [%s] must not contain '/'", segment, path))
+		}
+		constraint, err := newParamConstraint(segment[i+1 : len(segment)-1])
+		if err != nil {
+			return token, errors.New(fmt.Sprintf("param constraint [%s] in path [%s] is invalid: %v", segment, path, err))
+		}
+		token.name = segment[1:i]
+		token.constraint = constraint
+	}
+	if token.name == "" {
+		return token, errors.New(fmt.Sprintf("param in path [%s] must have a non-empty name", path))
+	}
+	return token, nil
+}
+
+// 按/切分路由，参数约束<...>中的/不作为分隔符(由parseParam报错)
+func splitSegments(path string) []string {
+	var segments []string
+	start, depth := 0, 0
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '<':
+			if path[start] == ':' {
+				depth++
+			}
+		case '>':
+			if depth > 0 {
+				depth--
+			}
+		case '/':
+			if depth == 0 {
+				segments = append(segments, path[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(segments, path[start:])
+}
+
+// 将路由切分为静态片段和参数/通配片段(/user/:id/info 切分为 /user/、:id、/info)
+func tokenizeRoute(path string) ([]routeToken, error) {
+	if path == "" || path[0] != '/' {
+		return nil, errors.New(fmt.Sprintf("path [%s] must begin with '/'", path))
+	}
+	var tokens []routeToken
+	var sb strings.Builder
+	segments := splitSegments(path[1:])
+	for i, segment := range segments {
+		sb.WriteByte('/')
+		var token routeToken
+		switch {
+		case len(segment) > 0 && segment[0] == ':':
+			var err error
+			if token, err = parseParam(path, segment); err != nil {
+				return nil, err
+			}
+		case segment == "*":
+			token = routeToken{nType: wildcard, text: segment}
+		case segment == "**":
+			if i != len(segments)-1 {
+				return nil, errors.New(fmt.Sprintf("'**' is only allowed at the end of path [%s]", path))
+			}
+			token = routeToken{nType: catchAll, text: segment}
+		default:
+			sb.WriteString(segment)
+			continue
+		}
+		tokens = append(tokens, routeToken{nType: static, text: sb.String()})
+		sb.Reset()
+		tokens = append(tokens, token)
+	}
+	if sb.Len() > 0 {
+		tokens = append(tokens, routeToken{nType: static, text: sb.String()})
+	}
+	// 同一个路由中参数名不能重复
+	names := make(map[string]bool)
+	for _, token := range tokens {
+		if token.nType != param {
+			continue
+		}
+		if names[token.name] {
+			return nil, errors.New(fmt.Sprintf("param [%s] appears more than once in path [%s]", token.name, path))
+		}
+		names[token.name] = true
+	}
+	return tokens, nil
+}
+
+// addRoute 注册路由，返回路由终点节点。与已有路由产生歧义时(如同一位置约束相同的参数名不同)返回error
+func (n *radixNode) addRoute(path string) (*radixNode, error) {
+	tokens, err := tokenizeRoute(path)
+	if err != nil {
+		return nil, err
+	}
+	n.priority++
+	for _, token := range tokens {
+		switch token.nType {
+		case static:
+			n = n.insertStatic(token.text)
+		case param:
+			child, err := n.insertParam(path, token)
+			if err != nil {
+				return nil, err
+			}
+			n = child
+			n.priority++
+		case wildcard:
+			if n.wildChild == nil {
+				n.wildChild = &radixNode{path: token.text, nType: wildcard}
+			}
+			n = n.wildChild
+			n.priority++
+		case catchAll:
+			if n.catchAllChild == nil {
+				n.catchAllChild = &radixNode{path: token.text, nType: catchAll}
+			}
+			n = n.catchAllChild
+			n.priority++
+		}
+	}
+	n.isEnd = true
+	n.fullPath = path
+	return n, nil
+}
+
+// 插入参数节点：同一位置可以有多个约束不同的参数节点，约束相同(包括都没有约束)但参数名不同时返回error
+func (n *radixNode) insertParam(path string, token routeToken) (*radixNode, error) {
+	for _, child := range n.paramChildren {
+		if child.path == token.text {
+			return child, nil
+		}
+		if child.constraint.String() == token.constraint.String() {
+			return nil, errors.New(fmt.Sprintf("param [%s] in path [%s] conflicts with existing param [%s] at the same position",
+				token.text, path, child.path))
+		}
+	}
+	child := &radixNode{path: token.text, nType: param, paramName: token.name, constraint: token.constraint}
+	if token.constraint == nil {
+		n.paramChildren = append(n.paramChildren, child)
+		return child, nil
+	}
+	// 有约束的参数节点放在没有约束的参数节点之前
+	i := len(n.paramChildren)
+	if i > 0 && n.paramChildren[i-1].constraint == nil {
+		i--
+	}
+	n.paramChildren = append(n.paramChildren[:i], append([]*radixNode{child}, n.paramChildren[i:]...)...)
+	return child, nil
+}
+
+// 插入静态片段，必要时拆分已有节点，返回静态片段结束位置的节点
+func (n *radixNode) insertStatic(path string) *radixNode {
+	for len(path) > 0 {
+		i := strings.IndexByte(n.indices, path[0])
+		if i == -1 {
+			child := &radixNode{path: path, nType: static, priority: 1}
+			n.indices += string(path[0])
+			n.children = append(n.children, child)
+			n.incrementChildPriority(len(n.children) - 1)
+			return child
+		}
+		i = n.incrementChildPriority(i)
+		child := n.children[i]
+		l := longestCommonPrefix(path, child.path)
+		if l < len(child.path) {
+			child.split(l)
+		}
+		n = child
+		path = path[l:]
+	}
+	return n
+}
+
+// 在位置i处将节点拆分为公共前缀节点和剩余部分的子节点
+func (n *radixNode) split(i int) {
+	rest := &radixNode{
+		path:          n.path[i:],
+		nType:         static,
+		indices:       n.indices,
+		children:      n.children,
+		paramChildren: n.paramChildren,
+		wildChild:     n.wildChild,
+		catchAllChild: n.catchAllChild,
+		priority:      n.priority - 1,
+		fullPath:      n.fullPath,
+		isEnd:         n.isEnd,
+	}
+	n.path = n.path[:i]
+	n.indices = string(rest.path[0])
+	n.children = []*radixNode{rest}
+	n.paramChildren = nil
+	n.wildChild = nil
+	n.catchAllChild = nil
+	n.fullPath = ""
+	n.isEnd = false
+}
+
+// 增加子节点的priority并将其向前移动，返回子节点移动后的位置
+func (n *radixNode) incrementChildPriority(i int) int {
+	n.children[i].priority++
+	priority := n.children[i].priority
+	newPos := i
+	for ; newPos > 0 && n.children[newPos-1].priority < priority; newPos-- {
+		n.children[newPos-1], n.children[newPos] = n.children[newPos], n.children[newPos-1]
+	}
+	if newPos != i {
+		n.indices = n.indices[:newPos] + n.indices[i:i+1] + n.indices[newPos:i] + n.indices[i+1:]
+	}
+	return newPos
+}
+
+// getValue 根据请求路径查找路由终点节点，匹配到的路由参数追加到params中
+func (n *radixNode) getValue(path string, params *Params) *radixNode {
+	return n.find(path, params, nil)
+}
+
+// find 与getValue相同，accept不为nil时路由终点还需要accept返回true才算匹配，否则回溯尝试低优先级的分支
+// (用于Host和请求头限制：某个Host的静态路由不能遮住另一个Host的参数路由)
+func (n *radixNode) find(path string, params *Params, accept func(node *radixNode) bool) *radixNode {
+	// 静态节点优先
+	if len(path) > 0 {
+		if i := strings.IndexByte(n.indices, path[0]); i != -1 {
+			child := n.children[i]
+			if strings.HasPrefix(path, child.path) {
+				if node := child.find(path[len(child.path):], params, accept); node != nil {
+					return node
+				}
+			}
+		}
+	} else if n.isEnd && (accept == nil || accept(n)) {
+		return n
+	}
+	// 然后是参数节点和*，二者都匹配一段非空的路径(不满足约束的参数节点直接跳过)
+	end := strings.IndexByte(path, '/')
+	if end == -1 {
+		end = len(path)
+	}
+	if end > 0 {
+		value := path[:end]
+		for _, child := range n.paramChildren {
+			if child.constraint != nil && !child.constraint.match(value) {
+				continue
+			}
+			*params = append(*params, Param{Key: child.paramName, Value: value})
+			if node := child.find(path[end:], params, accept); node != nil {
+				return node
+			}
+			*params = (*params)[:len(*params)-1]
+		}
+		if n.wildChild != nil {
+			*params = append(*params, Param{Key: n.wildChild.path, Value: value})
+			if node := n.wildChild.find(path[end:], params, accept); node != nil {
+				return node
+			}
+			*params = (*params)[:len(*params)-1]
+		}
+	}
+	// 最后是**，匹配剩余的全部路径
+	if n.catchAllChild != nil && n.catchAllChild.isEnd {
+		*params = append(*params, Param{Key: n.catchAllChild.path, Value: path})
+		if accept == nil || accept(n.catchAllChild) {
+			return n.catchAllChild
+		}
+		*params = (*params)[:len(*params)-1]
+	}
+	return nil
+}
+
+func longestCommonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
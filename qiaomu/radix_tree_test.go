@@ -0,0 +1,246 @@
+package qiaomu
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRadixNode(t *testing.T) {
+	root := &radixNode{}
+	routes := []string{
+		"/user/info",
+		"/user/:id",
+		"/user/:id/orders",
+		"/user/*/profile",
+		"/user/**",
+		"/users",
+		"/static/**",
+		"/log/*",
+		"/",
+	}
+	for _, route := range routes {
+		if _, err := root.addRoute(route); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var testcases = []struct {
+		path     string
+		fullPath string
+		params   Params
+	}{
+		{"/", "/", nil},
+		{"/user/info", "/user/info", nil},
+		{"/user/1", "/user/:id", Params{{Key: "id", Value: "1"}}},
+		{"/user/1/orders", "/user/:id/orders", Params{{Key: "id", Value: "1"}}},
+		{"/user/1/profile", "/user/*/profile", Params{{Key: "*", Value: "1"}}},
+		{"/user/1/2/3", "/user/**", Params{{Key: "**", Value: "1/2/3"}}},
+		{"/users", "/users", nil},
+		{"/static/", "/static/**", Params{{Key: "**", Value: ""}}},
+		{"/static/css/app.css", "/static/**", Params{{Key: "**", Value: "css/app.css"}}},
+		{"/log/error", "/log/*", Params{{Key: "*", Value: "error"}}},
+		{"/log/error/1", "", nil},
+		{"/order", "", nil},
+	}
+	for _, testcase := range testcases {
+		var params Params
+		node := root.getValue(testcase.path, &params)
+		if node == nil {
+			if testcase.fullPath != "" {
+				t.Errorf("%s: got nil, want %s", testcase.path, testcase.fullPath)
+			}
+			continue
+		}
+		if node.fullPath != testcase.fullPath {
+			t.Errorf("%s: got %s, want %s", testcase.path, node.fullPath, testcase.fullPath)
+		}
+		if fmt.Sprint(params) != fmt.Sprint(testcase.params) {
+			t.Errorf("%s: got params %v, want %v", testcase.path, params, testcase.params)
+		}
+	}
+}
+
+func TestRadixNodeRegisterOrder(t *testing.T) {
+	// 静态路由优先于参数路由，与注册顺序无关
+	for _, routes := range [][]string{
+		{"/user/:id", "/user/info"},
+		{"/user/info", "/user/:id"},
+	} {
+		root := &radixNode{}
+		for _, route := range routes {
+			if _, err := root.addRoute(route); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var params Params
+		if node := root.getValue("/user/info", &params); node == nil || node.fullPath != "/user/info" {
+			t.Errorf("%v: /user/info matched %v", routes, node)
+		}
+		params = params[:0]
+		if node := root.getValue("/user/1", &params); node == nil || node.fullPath != "/user/:id" {
+			t.Errorf("%v: /user/1 matched %v", routes, node)
+		}
+	}
+}
+
+func TestRadixNodeParamConstraint(t *testing.T) {
+	root := &radixNode{}
+	routes := []string{
+		"/user/:name",
+		"/user/:id<int>",
+		"/user/:uuid<uuid>",
+		"/post/:slug<[a-z-]+>",
+		"/post/:id<int>/comments",
+	}
+	for _, route := range routes {
+		if _, err := root.addRoute(route); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var testcases = []struct {
+		path     string
+		fullPath string
+		params   Params
+	}{
+		{"/user/10", "/user/:id<int>", Params{{Key: "id", Value: "10"}}},
+		{"/user/abc", "/user/:name", Params{{Key: "name", Value: "abc"}}},
+		{"/user/6ba7b810-9dad-11d1-80b4-00c04fd430c8", "/user/:uuid<uuid>", Params{{Key: "uuid", Value: "6ba7b810-9dad-11d1-80b4-00c04fd430c8"}}},
+		{"/post/hello-world", "/post/:slug<[a-z-]+>", Params{{Key: "slug", Value: "hello-world"}}},
+		{"/post/Hello", "", nil},
+		{"/post/1/comments", "/post/:id<int>/comments", Params{{Key: "id", Value: "1"}}},
+		{"/post/abc/comments", "", nil},
+	}
+	for _, testcase := range testcases {
+		var params Params
+		node := root.getValue(testcase.path, &params)
+		if node == nil {
+			if testcase.fullPath != "" {
+				t.Errorf("%s: got nil, want %s", testcase.path, testcase.fullPath)
+			}
+			continue
+		}
+		if node.fullPath != testcase.fullPath {
+			t.Errorf("%s: got %s, want %s", testcase.path, node.fullPath, testcase.fullPath)
+		}
+		if fmt.Sprint(params) != fmt.Sprint(testcase.params) {
+			t.Errorf("%s: got params %v, want %v", testcase.path, params, testcase.params)
+		}
+	}
+}
+
+func TestRadixNodeConflict(t *testing.T) {
+	var testcases = []struct {
+		routes []string
+		err    bool
+	}{
+		{[]string{"/user/:id", "/user/:name"}, true},
+		{[]string{"/user/:id/info", "/user/:name/orders"}, true},
+		{[]string{"/user/**/info"}, true},
+		{[]string{"/user/:"}, true},
+		{[]string{"/user/:id/:id"}, true},
+		{[]string{"user"}, true},
+		{[]string{"/user/:id", "/user/*", "/user/**"}, false},
+		{[]string{"/user/:id", "/user/:id/info"}, false},
+		{[]string{"/user/:id<int>", "/user/:num<int>"}, true},
+		{[]string{"/user/:id<[a-z>"}, true},
+		{[]string{"/user/:id<int"}, true},
+		{[]string{"/user/:id<int>", "/user/:name", "/user/:slug<[a-z]+>"}, false},
+		{[]string{"/file/:p<re:a/b>"}, true},
+		{[]string{"/file/:p<re:a/b>/info"}, true},
+		{[]string{"/file/:p<re:(?P<x>a+)>/info"}, false},
+	}
+	for _, testcase := range testcases {
+		root := &radixNode{}
+		var err error
+		for _, route := range testcase.routes {
+			if _, err = root.addRoute(route); err != nil {
+				break
+			}
+		}
+		if (err != nil) != testcase.err {
+			t.Errorf("%v: got error %v, want error %v", testcase.routes, err, testcase.err)
+		}
+	}
+}
+
+var benchRoutes = []string{
+	"/user/info",
+	"/user/login",
+	"/user/logout",
+	"/user/get/:id",
+	"/user/create/hello",
+	"/order/get/:id",
+	"/order/list",
+	"/order/detail/:id/items",
+	"/goods/find",
+	"/goods/category/:category/list",
+	"/static/**",
+}
+
+var benchPaths = []string{
+	"/user/info",
+	"/user/get/1000",
+	"/order/detail/42/items",
+	"/goods/category/phone/list",
+	"/static/js/app.js",
+}
+
+func BenchmarkRadixNode(b *testing.B) {
+	root := &radixNode{}
+	for _, route := range benchRoutes {
+		root.addRoute(route)
+	}
+	params := make(Params, 0, 4)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, path := range benchPaths {
+			params = params[:0]
+			root.getValue(path, &params)
+		}
+	}
+}
+
+func BenchmarkTreeNode(b *testing.B) {
+	root := &treeNode{name: "/", children: make([]*treeNode, 0)}
+	for _, route := range benchRoutes {
+		root.Put(route)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, path := range benchPaths {
+			root.Get(path)
+		}
+	}
+}
+
+func BenchmarkRadixNodeStatic(b *testing.B) {
+	root := &radixNode{}
+	for i := 0; i < 100; i++ {
+		root.addRoute(fmt.Sprintf("/api/v1/resource%d/list", i))
+	}
+	path := "/api/v1/resource99/list"
+	params := make(Params, 0, 4)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		params = params[:0]
+		root.getValue(path, &params)
+	}
+}
+
+func BenchmarkTreeNodeStatic(b *testing.B) {
+	root := &treeNode{name: "/", children: make([]*treeNode, 0)}
+	for i := 0; i < 100; i++ {
+		root.Put(strings.Join([]string{"/api/v1/resource", fmt.Sprint(i), "/list"}, ""))
+	}
+	path := "/api/v1/resource99/list"
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		root.Get(path)
+	}
+}
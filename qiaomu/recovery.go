@@ -10,13 +10,13 @@ import (
 	"github.com/qingbo1011/qiaomu/qerror"
 )
 
-// Recovery 错误处理中间件
+// Recovery 错误处理中间件(发生panic后终止处理链)
 func Recovery(next HandlerFunc) HandlerFunc {
 	return func(ctx *Context) {
 		defer func() {
 			if err := recover(); err != nil {
-				err2 := err.(error)
-				if err2 != nil {
+				ctx.Abort()
+				if err2, ok := err.(error); ok {
 					var qError *qerror.QError
 					if errors.As(err2, &qError) {
 						qError.ExecResult()
@@ -58,6 +58,18 @@ func (r *QueenEtcdRegister) RegisterService(serviceName string, host string, por
 	return err
 }
 
+// DeregisterService 注销服务(只有当前注册的地址是host:port时才删除，避免误删其他实例重新注册的地址)
+func (r *QueenEtcdRegister) DeregisterService(serviceName string, host string, port int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	addr := fmt.Sprintf("%s:%d", host, port)
+	_, err := r.cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.Value(serviceName), "=", addr)).
+		Then(clientv3.OpDelete(serviceName)).
+		Commit()
+	return err
+}
+
 func (r *QueenEtcdRegister) GetValue(serviceName string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
@@ -22,3 +22,9 @@ type QueenRegister interface {
 	GetValue(serviceName string) (string, error)
 	Close() error
 }
+
+// Deregisterer 支持注销服务的注册中心客户端(QueenEtcdRegister、QueenNacosRegister均实现)，
+// 未实现的QueenRegister在关闭时不注销
+type Deregisterer interface {
+	DeregisterService(serviceName string, host string, port int) error
+}
@@ -0,0 +1,79 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// SSEvent Server-Sent Events中的一个事件
+type SSEvent struct {
+	Event string // 事件类型(为空时客户端按message事件处理)
+	Id    string // 事件id，客户端重连时通过请求头Last-Event-ID带上最后收到的id
+	Retry uint   // 客户端重连间隔(毫秒)，为0时不发送
+	Data  any    // 事件数据：string和[]byte原样发送，其他类型编码为JSON
+}
+
+// Render 写入事件并flush，不调用WriteHeader(第一次写入时自动返回200)，因此可以在同一个响应中多次调用
+func (s *SSEvent) Render(w http.ResponseWriter, code int) error {
+	s.WriteContentType(w)
+	if err := s.Encode(w); err != nil {
+		return err
+	}
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+func (s *SSEvent) WriteContentType(w http.ResponseWriter) {
+	header := w.Header()
+	if header.Get("Content-Type") == "text/event-stream" {
+		return
+	}
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no") // 关闭nginx的响应缓冲
+}
+
+// Encode 按text/event-stream格式编码事件
+func (s *SSEvent) Encode(w io.Writer) error {
+	var sb strings.Builder
+	if s.Id != "" {
+		sb.WriteString("id: " + removeNewlines(s.Id) + "\n")
+	}
+	if s.Event != "" {
+		sb.WriteString("event: " + removeNewlines(s.Event) + "\n")
+	}
+	if s.Retry > 0 {
+		sb.WriteString(fmt.Sprintf("retry: %d\n", s.Retry))
+	}
+	var data string
+	switch v := s.Data.(type) {
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		jsonData, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(jsonData)
+	}
+	// 多行数据每行一个data字段
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		sb.WriteString("data: " + line + "\n")
+	}
+	sb.WriteString("\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func removeNewlines(s string) string {
+	return strings.NewReplacer("\n", "", "\r", "").Replace(s)
+}
@@ -0,0 +1,72 @@
+package qiaomu
+
+import (
+	"github.com/qingbo1011/qiaomu/internal/requestid"
+	qlog "github.com/qingbo1011/qiaomu/log"
+)
+
+// 请求ID最大长度，超过时或包含不可见字符时重新生成(避免伪造的请求头污染日志)
+const maxRequestIDLength = 128
+
+// RequestIDConfig 请求ID中间件配置
+type RequestIDConfig struct {
+	Header    string        // 读取和返回请求ID的请求头(默认X-Request-ID)
+	Generator func() string // 请求没有携带请求ID时的生成函数(默认32位十六进制随机字符串)
+}
+
+// RequestID 请求ID中间件(默认配置)
+func RequestID(next HandlerFunc) HandlerFunc {
+	return RequestIDWithConfig(RequestIDConfig{})(next)
+}
+
+// RequestIDWithConfig 请求ID中间件：使用请求携带的请求ID或生成新的请求ID，保存到Context中(ctx.RequestID())，
+// 添加到本次请求的ctx.Logger字段(request_id)和响应头中，并放入ctx.R.Context()，
+// 调用rpc时传入ctx(rpc.QueenHttpClientSession.WithContext、QueenTcpClient.Invoke)即可将请求ID转发给下游服务
+func RequestIDWithConfig(conf RequestIDConfig) MiddlewareFunc {
+	header := conf.Header
+	if header == "" {
+		header = requestid.Header
+	}
+	generator := conf.Generator
+	if generator == nil {
+		generator = requestid.New
+	}
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			id := ctx.R.Header.Get(header)
+			if !validRequestID(id) {
+				id = generator()
+				ctx.R.Header.Set(header, id)
+			}
+			ctx.requestID = id
+			ctx.R = ctx.R.WithContext(requestid.NewContext(ctx.R.Context(), id))
+			ctx.W.Header().Set(header, id)
+			if ctx.Logger != nil {
+				fields := make(qlog.Fields, len(ctx.Logger.LoggerFields)+1)
+				for k, v := range ctx.Logger.LoggerFields {
+					fields[k] = v
+				}
+				fields["request_id"] = id
+				ctx.Logger = ctx.Logger.WithFields(fields)
+			}
+			next(ctx)
+		}
+	}
+}
+
+// RequestID 本次请求的请求ID(使用了RequestID中间件时)
+func (c *Context) RequestID() string {
+	return c.requestID
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
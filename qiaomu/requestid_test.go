@@ -0,0 +1,52 @@
+package qiaomu
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	qlog "github.com/qingbo1011/qiaomu/log"
+	"github.com/qingbo1011/qiaomu/rpc"
+)
+
+func TestRequestID(t *testing.T) {
+	// 下游服务：返回收到的请求ID
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Request-ID")))
+	}))
+	defer downstream.Close()
+
+	engine := New()
+	engine.Logger = qlog.New()
+	engine.Use(RequestID)
+	var fields any
+	engine.Group("order").Get("/create", func(ctx *Context) {
+		fields = ctx.Logger.LoggerFields["request_id"]
+		body, err := rpc.NewHttpClient().Session().WithContext(ctx.R.Context()).Get(downstream.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx.String(http.StatusOK, ctx.RequestID()+" "+string(body))
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/order/create", nil)
+	r.Header.Set("X-Request-ID", "order-123")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	if w.Body.String() != "order-123 order-123" || w.Header().Get("X-Request-ID") != "order-123" || fields != "order-123" {
+		t.Errorf("got body %q, header %q, field %v", w.Body.String(), w.Header().Get("X-Request-ID"), fields)
+	}
+	if _, ok := engine.Logger.LoggerFields["request_id"]; ok {
+		t.Error("request_id leaked into the engine logger")
+	}
+
+	// 没有携带或携带了非法的请求ID时重新生成
+	r = httptest.NewRequest(http.MethodGet, "/order/create", nil)
+	r.Header.Set("X-Request-ID", "bad id\n")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	id := w.Header().Get("X-Request-ID")
+	if len(id) != 32 || w.Body.String() != id+" "+id {
+		t.Errorf("got generated id %q, body %q", id, w.Body.String())
+	}
+}
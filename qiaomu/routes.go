@@ -0,0 +1,100 @@
+package qiaomu
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+)
+
+// RouteInfo 已注册路由的信息
+type RouteInfo struct {
+	Method      string            `json:"method"`
+	Path        string            `json:"path"`                  // 完整路由(如/user/:id)
+	Name        string            `json:"name,omitempty"`        // 路由名称(见Route.Name)
+	Handler     string            `json:"handler"`               // 路由处理函数名称
+	Host        string            `json:"host,omitempty"`        // Host限制(见routerGroup.Host)
+	Headers     map[string]string `json:"headers,omitempty"`     // 请求头限制(见routerGroup.Header和Route.Header)
+	Constraints map[string]string `json:"constraints,omitempty"` // 路由参数约束(如/user/:id<int>为id:int)，不满足约束的请求不会匹配到该路由
+	Middlewares []string          `json:"middlewares"`           // 按执行顺序排列的中间件名称(包括全局、父路由组、路由组和路由级中间件)
+}
+
+// Routes 按注册顺序返回所有已注册的路由
+func (e *Engine) Routes() []RouteInfo {
+	e.checkPending()
+	routes := make([]RouteInfo, 0, len(e.routeList))
+	for _, rt := range e.routeList {
+		middlewares := make([]string, 0)
+		for _, m := range orderMiddlewares(rt.allMiddlewares()) {
+			middlewares = append(middlewares, m.name)
+		}
+		routes = append(routes, RouteInfo{
+			Method:      rt.method,
+			Path:        rt.fullPath,
+			Name:        rt.routeName,
+			Handler:     rt.handler,
+			Host:        rt.hostPattern(),
+			Headers:     rt.headerConstraints(),
+			Constraints: paramConstraints(rt.fullPath),
+			Middlewares: middlewares,
+		})
+	}
+	return routes
+}
+
+// DebugRoutes 注册以JSON格式返回路由表的接口(默认为GET /debug/routes)，接口经过全局中间件(可以在全局中间件中做鉴权)
+func (e *Engine) DebugRoutes(path ...string) {
+	p := "/debug/routes"
+	if len(path) > 0 {
+		p = path[0]
+	}
+	e.Group("").Get(p, func(ctx *Context) {
+		ctx.JSON(http.StatusOK, e.Routes())
+	})
+}
+
+// 路由中的参数约束
+func paramConstraints(fullPath string) map[string]string {
+	tokens, _ := tokenizeRoute(fullPath)
+	var constraints map[string]string
+	for _, token := range tokens {
+		if token.constraint == nil {
+			continue
+		}
+		if constraints == nil {
+			constraints = make(map[string]string)
+		}
+		constraints[token.name] = token.constraint.String()
+	}
+	return constraints
+}
+
+// 打印路由表
+func (e *Engine) printRoutes() {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "registered routes:")
+	for _, rt := range e.Routes() {
+		fmt.Fprintf(w, "%s\t%s\t--> %s\t(%d middlewares)\n", rt.Method, rt.Path, rt.Handler, len(rt.Middlewares))
+	}
+	w.Flush()
+	table := strings.TrimSuffix(buf.String(), "\n")
+	if e.Logger != nil {
+		e.Logger.Debug(table)
+		return
+	}
+	log.Println(table)
+}
+
+// 获取函数名称(如github.com/qingbo1011/qiaomu.Logging)
+func nameOfFunction(f any) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer())
+	if fn == nil {
+		return ""
+	}
+	return fn.Name()
+}
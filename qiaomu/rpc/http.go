@@ -3,6 +3,7 @@ package rpc
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,6 +14,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/qingbo1011/qiaomu/internal/requestid"
 	qlog "github.com/qingbo1011/qiaomu/log"
 )
 
@@ -75,7 +77,7 @@ func (c *QueenHttpClientSession) Get(url string, args map[string]any) ([]byte, e
 	}
 	logger := qlog.Default()
 	logger.Info(url)
-	request, err := http.NewRequest("GET", url, nil)
+	request, err := http.NewRequestWithContext(c.context(), "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -84,7 +86,7 @@ func (c *QueenHttpClientSession) Get(url string, args map[string]any) ([]byte, e
 }
 
 func (c *QueenHttpClientSession) PostForm(url string, args map[string]any) ([]byte, error) {
-	request, err := http.NewRequest("POST", url, strings.NewReader(c.toValues(args)))
+	request, err := http.NewRequestWithContext(c.context(), "POST", url, strings.NewReader(c.toValues(args)))
 	if err != nil {
 		return nil, err
 	}
@@ -93,7 +95,7 @@ func (c *QueenHttpClientSession) PostForm(url string, args map[string]any) ([]by
 
 func (c *QueenHttpClientSession) PostJson(url string, args map[string]any) ([]byte, error) {
 	marshal, _ := json.Marshal(args)
-	request, err := http.NewRequest("POST", url, bytes.NewReader(marshal))
+	request, err := http.NewRequestWithContext(c.context(), "POST", url, bytes.NewReader(marshal))
 	if err != nil {
 		return nil, err
 	}
@@ -101,7 +103,17 @@ func (c *QueenHttpClientSession) PostJson(url string, args map[string]any) ([]by
 }
 
 func (c *QueenHttpClientSession) responseHandle(request *http.Request) ([]byte, error) {
-	c.ReqHandler(request)
+	// 转发请求ID(请求本身的context优先于会话的context)
+	id := requestid.FromContext(request.Context())
+	if id == "" {
+		id = requestid.FromContext(c.ctx)
+	}
+	if id != "" && request.Header.Get(requestid.Header) == "" {
+		request.Header.Set(requestid.Header, id)
+	}
+	if c.ReqHandler != nil {
+		c.ReqHandler(request)
+	}
 	response, err := c.client.Do(request)
 	if err != nil {
 		return nil, err
@@ -167,6 +179,30 @@ type QueenService interface {
 type QueenHttpClientSession struct {
 	*QueenHttpClient
 	ReqHandler func(req *http.Request)
+	ctx        context.Context
+}
+
+// WithContext 设置会话发出请求使用的context，context中的请求ID(见WithRequestID)会通过X-Request-ID请求头转发给下游服务
+func (c *QueenHttpClientSession) WithContext(ctx context.Context) *QueenHttpClientSession {
+	c.ctx = ctx
+	return c
+}
+
+func (c *QueenHttpClientSession) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// WithRequestID 返回携带请求ID的context，用于向下游服务转发请求ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return requestid.NewContext(ctx, id)
+}
+
+// RequestIDFromContext 获取context中的请求ID(服务方法的第一个参数为context.Context时，可以获取调用方转发的请求ID)
+func RequestIDFromContext(ctx context.Context) string {
+	return requestid.FromContext(ctx)
 }
 
 func (c *QueenHttpClient) RegisterHttpService(name string, service QueenService) {
@@ -174,9 +210,7 @@ func (c *QueenHttpClient) RegisterHttpService(name string, service QueenService)
 }
 
 func (c *QueenHttpClient) Session() *QueenHttpClientSession {
-	return &QueenHttpClientSession{
-		c, nil,
-	}
+	return &QueenHttpClientSession{QueenHttpClient: c}
 }
 
 func (c *QueenHttpClientSession) Do(service string, method string) QueenService {
@@ -16,6 +16,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/qingbo1011/qiaomu/internal/requestid"
 	"github.com/qingbo1011/qiaomu/register"
 	"golang.org/x/time/rate"
 	"google.golang.org/protobuf/proto"
@@ -144,6 +145,7 @@ type QueenRpcRequest struct {
 	ServiceName string
 	MethodName  string
 	Args        []any
+	XRequestId  string // 调用方转发的请求ID(X-Request-ID)
 }
 
 type QueenRpcResponse struct {
@@ -341,11 +343,11 @@ func (s *QueenTcpServer) readHandle(conn *QueenTcpConn) {
 				return
 			}
 			//调用方法
-			args := make([]reflect.Value, len(req.Args))
+			args, offset := contextArgs(method, req.XRequestId)
 			for i := range req.Args {
 				of := reflect.ValueOf(req.Args[i].AsInterface())
-				of = of.Convert(method.Type().In(i))
-				args[i] = of
+				of = of.Convert(method.Type().In(i + offset))
+				args = append(args, of)
 			}
 			result := method.Call(args)
 
@@ -388,7 +390,7 @@ func (s *QueenTcpServer) readHandle(conn *QueenTcpConn) {
 			}
 			//调用方法
 			args := req.Args
-			var valuesArg []reflect.Value
+			valuesArg, _ := contextArgs(method, req.XRequestId)
 			for _, v := range args {
 				valuesArg = append(valuesArg, reflect.ValueOf(v))
 			}
@@ -412,6 +414,21 @@ func (s *QueenTcpServer) readHandle(conn *QueenTcpConn) {
 	}
 }
 
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// 服务方法的第一个参数为context.Context时，传入携带调用方请求ID的context，返回该参数和其余参数的偏移量
+func contextArgs(method reflect.Value, xRequestId string) ([]reflect.Value, int) {
+	t := method.Type()
+	if t.NumIn() == 0 || t.In(0) != contextType {
+		return nil, 0
+	}
+	ctx := context.Background()
+	if xRequestId != "" {
+		ctx = requestid.NewContext(ctx, xRequestId)
+	}
+	return []reflect.Value{reflect.ValueOf(ctx)}, 1
+}
+
 func (s *QueenTcpServer) writeHandle(conn *QueenTcpConn) {
 	select {
 	case rsp := <-conn.rspChan:
@@ -616,12 +633,17 @@ func (c *QueenTcpClient) Close() error {
 var reqId int64
 
 func (c *QueenTcpClient) Invoke(ctx context.Context, serviceName string, methodName string, args []any) (any, error) {
+	conn := c.conn
+	if conn == nil {
+		return nil, errors.New("rpc: connection is closed, call Connect first")
+	}
 	// 包装request对象，编码发送即可
 	req := &QueenRpcRequest{}
 	req.RequestId = atomic.AddInt64(&reqId, 1)
 	req.ServiceName = serviceName
 	req.MethodName = methodName
 	req.Args = args
+	req.XRequestId = requestid.FromContext(ctx)
 
 	headers := make([]byte, 17)
 
@@ -644,6 +666,7 @@ func (c *QueenTcpClient) Invoke(ctx context.Context, serviceName string, methodN
 		pReq.RequestId = atomic.AddInt64(&reqId, 1)
 		pReq.ServiceName = serviceName
 		pReq.MethodName = methodName
+		pReq.XRequestId = req.XRequestId
 		listValue, err := structpb.NewList(args)
 		if err != nil {
 			return nil, err
@@ -667,33 +690,47 @@ func (c *QueenTcpClient) Invoke(ctx context.Context, serviceName string, methodN
 	}
 	fullLen := 17 + len(body)
 	binary.BigEndian.PutUint32(headers[2:6], uint32(fullLen))
+	// 调用方context的截止时间同样作为连接读写的截止时间，调用结束后清除，不影响之后的调用
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
 
-	_, err = c.conn.Write(headers[:])
+	_, err = conn.Write(headers[:])
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = c.conn.Write(body[:])
+	_, err = conn.Write(body[:])
 	if err != nil {
 		return nil, err
 	}
-	rspChan := make(chan *QueenRpcResponse)
-	go c.readHandle(rspChan)
-	rsp := <-rspChan
-	return rsp, nil
+	rspChan := make(chan *QueenRpcResponse, 1)
+	go c.readHandle(conn, rspChan)
+	select {
+	case rsp := <-rspChan:
+		return rsp, nil
+	case <-ctx.Done():
+		// 响应还没有读取完，连接上的数据流已经无法与之后的请求对应，关闭连接(之后需要重新Connect)
+		conn.Close()
+		c.conn = nil
+		return nil, ctx.Err()
+	}
 }
 
-func (c *QueenTcpClient) readHandle(rspChan chan *QueenRpcResponse) {
+func (c *QueenTcpClient) readHandle(conn net.Conn, rspChan chan *QueenRpcResponse) {
 	defer func() {
 		if err := recover(); err != nil {
 			log.Println("QueenTcpClient readHandle recover: ", err)
-			c.conn.Close()
+			conn.Close()
 		}
 	}()
 	for {
-		msg, err := decodeFrame(c.conn)
+		msg, err := decodeFrame(conn)
 		if err != nil {
 			log.Println("未解析出任何数据")
+			// 读取失败(如超时)时可能只读了一部分响应，连接不能再用
+			conn.Close()
 			rsp := &QueenRpcResponse{}
 			rsp.Code = 500
 			rsp.Msg = err.Error()
@@ -811,7 +848,7 @@ func (p *QueenTcpClientProxy) Call(ctx context.Context, serviceName string, meth
 	for i := 0; i < p.option.Retries; i++ {
 		result, err := client.Invoke(ctx, serviceName, methodName, args)
 		if err != nil {
-			if i >= p.option.Retries-1 {
+			if i >= p.option.Retries-1 || ctx.Err() != nil {
 				log.Println(errors.New("already retry all time"))
 				client.Close()
 				return nil, err
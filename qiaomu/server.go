@@ -0,0 +1,343 @@
+package qiaomu
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/qingbo1011/qiaomu/register"
+)
+
+const defaultShutdownTimeout = 10 * time.Second
+
+// ServerOptions Engine创建http.Server时使用的配置(零值表示使用net/http的默认值)
+type ServerOptions struct {
+	ReadTimeout       time.Duration // 读取整个请求(包括body)的超时时间
+	ReadHeaderTimeout time.Duration // 读取请求头的超时时间
+	WriteTimeout      time.Duration // 写响应的超时时间
+	IdleTimeout       time.Duration // keep-alive连接的空闲超时时间
+	MaxHeaderBytes    int           // 请求头的最大字节数
+	ErrorLog          *log.Logger   // http.Server内部错误的日志
+	TLSConfig         *tls.Config   // https配置(设置了Certificates时可以不传证书文件)
+}
+
+// ListenConfig 监听配置，一个Engine可以同时在多个地址上提供服务
+type ListenConfig struct {
+	Network         string       // 网络类型，tcp(默认)或unix
+	Addr            string       // 监听地址(unix时为socket文件路径)
+	Listener        net.Listener // 已创建好的listener，设置后忽略Network和Addr
+	CertFile        string       // CertFile和KeyFile均不为空时以https方式服务
+	KeyFile         string
+	TLS             bool // 使用Server.TLSConfig中的证书以https方式服务
+	RedirectToHTTPS bool // 仅对http生效：不处理请求，而是重定向到同时启动的https服务
+}
+
+func (l ListenConfig) isTLS() bool {
+	return l.TLS || (l.CertFile != "" && l.KeyFile != "")
+}
+
+// 一个待启动的http.Server
+type serverRunner struct {
+	server   *http.Server
+	listener net.Listener
+	serve    func() error
+}
+
+// 启动失败时关闭已经创建的listener
+func closeListeners(runners []serverRunner) {
+	for _, runner := range runners {
+		runner.listener.Close()
+	}
+}
+
+// HookFunc 生命周期钩子
+type HookFunc func(ctx context.Context) error
+
+// OnStart 注册启动钩子，在开始监听端口之前按注册顺序执行，任意一个返回error则不再启动
+func (e *Engine) OnStart(hooks ...HookFunc) {
+	e.onStart = append(e.onStart, hooks...)
+}
+
+// OnStop 注册关闭钩子，在处理中的请求完成后按注册的逆序执行(如注销RegisterCli中的服务、关闭ORM、Release协程池)
+func (e *Engine) OnStop(hooks ...HookFunc) {
+	e.onStop = append(e.onStop, hooks...)
+}
+
+// Run 启动http服务，收到SIGINT/SIGTERM信号后优雅关闭
+func (e *Engine) Run(addr string) {
+	err := e.RunContext(context.Background(), addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// RunContext 启动http服务并阻塞，直到ctx结束、收到SIGINT/SIGTERM信号或者Shutdown被调用，之后等待处理中的请求完成并执行关闭钩子
+func (e *Engine) RunContext(ctx context.Context, addr string) error {
+	return e.RunListens(ctx, ListenConfig{Addr: addr})
+}
+
+// RunTLS 支持https
+func (e *Engine) RunTLS(addr, certFile, keyFile string) {
+	err := e.RunListens(context.Background(), ListenConfig{Addr: addr, CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// RunListener 在已创建好的listener上提供服务(如负载均衡器要求的unix socket)，关闭流程同RunContext
+func (e *Engine) RunListener(listener net.Listener) error {
+	return e.RunListens(context.Background(), ListenConfig{Listener: listener})
+}
+
+// RunUnix 在unix socket文件上提供服务(会先删除残留的socket文件)
+func (e *Engine) RunUnix(file string) error {
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return e.RunListens(context.Background(), ListenConfig{Network: "unix", Addr: file})
+}
+
+// RunListens 同时在多个地址上提供服务(如同时提供http和https，http可以设置为重定向到https)，
+// 任意一个服务启动失败时关闭全部服务，关闭流程同RunContext
+func (e *Engine) RunListens(ctx context.Context, configs ...ListenConfig) error {
+	if len(configs) == 0 {
+		return errors.New("at least one listen config is required")
+	}
+	httpsPort := ""
+	for _, config := range configs {
+		if config.isTLS() {
+			httpsPort = listenPort(config)
+			break
+		}
+	}
+	runners := make([]serverRunner, 0, len(configs))
+	for _, config := range configs {
+		var handler http.Handler = e
+		if config.RedirectToHTTPS && !config.isTLS() {
+			if httpsPort == "" {
+				return errors.New("RedirectToHTTPS requires an https listen config")
+			}
+			handler = httpsRedirectHandler(httpsPort)
+		}
+		runner, err := e.newServerRunner(config, handler)
+		if err != nil {
+			closeListeners(runners)
+			return err
+		}
+		runners = append(runners, runner)
+	}
+	return e.serve(ctx, runners)
+}
+
+// 根据Server配置创建http.Server
+func (e *Engine) newServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       e.Server.ReadTimeout,
+		ReadHeaderTimeout: e.Server.ReadHeaderTimeout,
+		WriteTimeout:      e.Server.WriteTimeout,
+		IdleTimeout:       e.Server.IdleTimeout,
+		MaxHeaderBytes:    e.Server.MaxHeaderBytes,
+		ErrorLog:          e.Server.ErrorLog,
+		TLSConfig:         e.Server.TLSConfig,
+	}
+}
+
+func (e *Engine) newServerRunner(config ListenConfig, handler http.Handler) (serverRunner, error) {
+	server := e.newServer(config.Addr, handler)
+	listener := config.Listener
+	if listener == nil {
+		network := config.Network
+		if network == "" {
+			network = "tcp"
+		}
+		addr := config.Addr
+		if network == "tcp" && addr == "" {
+			addr = ":http"
+			if config.isTLS() {
+				addr = ":https"
+			}
+		}
+		var err error
+		listener, err = net.Listen(network, addr)
+		if err != nil {
+			return serverRunner{}, err
+		}
+	}
+	runner := serverRunner{server: server, listener: listener}
+	if config.isTLS() {
+		runner.serve = func() error {
+			return server.ServeTLS(listener, config.CertFile, config.KeyFile)
+		}
+	} else {
+		runner.serve = func() error {
+			return server.Serve(listener)
+		}
+	}
+	return runner, nil
+}
+
+// 监听配置中的端口
+func listenPort(config ListenConfig) string {
+	addr := config.Addr
+	if config.Listener != nil {
+		addr = config.Listener.Addr().String()
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return ""
+	}
+	return port
+}
+
+// 将http请求重定向到https服务(GET/HEAD使用301，其他请求方式使用308以保留请求方式和body)
+func httpsRedirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+		code := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			code = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), code)
+	})
+}
+
+// Handler 返回Handler
+func (e *Engine) Handler() http.Handler {
+	e.checkPending()
+	return e
+}
+
+// Shutdown 优雅关闭：从注册中心注销RegisterOption中的服务，停止接收新的请求，等待处理中的请求完成(最长等到ctx结束)，然后按注册的逆序执行关闭钩子
+// 多次调用只会关闭一次
+func (e *Engine) Shutdown(ctx context.Context) error {
+	e.shutdownOnce.Do(func() {
+		e.serverLock.Lock()
+		servers := e.servers
+		e.serverLock.Unlock()
+		// 先从注册中心注销，不再有新的请求被路由到当前实例
+		if err := e.deregister(); err != nil && e.shutdownErr == nil {
+			e.shutdownErr = err
+		}
+		for _, server := range servers {
+			if err := server.Shutdown(ctx); err != nil && e.shutdownErr == nil {
+				e.shutdownErr = err
+			}
+		}
+		for i := len(e.onStop) - 1; i >= 0; i-- {
+			if err := e.onStop[i](ctx); err != nil && e.shutdownErr == nil {
+				e.shutdownErr = err
+			}
+		}
+		if e.ownRegisterCli {
+			if err := e.RegisterCli.Close(); err != nil && e.shutdownErr == nil {
+				e.shutdownErr = err
+			}
+		}
+		close(e.done)
+	})
+	return e.shutdownErr
+}
+
+func (e *Engine) serve(ctx context.Context, runners []serverRunner) error {
+	e.checkPending()
+	if err := e.createRegisterCli(); err != nil {
+		closeListeners(runners)
+		return err
+	}
+	if e.Debug {
+		e.printRoutes()
+	}
+	for _, hook := range e.onStart {
+		if err := hook(ctx); err != nil {
+			closeListeners(runners)
+			return err
+		}
+	}
+	e.serverLock.Lock()
+	for _, runner := range runners {
+		e.servers = append(e.servers, runner.server)
+	}
+	e.serverLock.Unlock()
+
+	errChan := make(chan error, len(runners))
+	for _, runner := range runners {
+		go func(serve func() error) {
+			errChan <- serve()
+		}(runner.serve)
+	}
+	signalCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	select {
+	case err := <-errChan:
+		if errors.Is(err, http.ErrServerClosed) {
+			// 其他地方调用了Shutdown，等待其完成
+			<-e.done
+			return e.shutdownErr
+		}
+		// 启动失败(如端口被占用)，同样执行关闭钩子释放资源
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), e.shutdownTimeout())
+		defer cancel()
+		e.Shutdown(shutdownCtx)
+		return err
+	case <-signalCtx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), e.shutdownTimeout())
+		defer cancel()
+		return e.Shutdown(shutdownCtx)
+	}
+}
+
+func (e *Engine) shutdownTimeout() time.Duration {
+	if e.ShutdownTimeout <= 0 {
+		return defaultShutdownTimeout
+	}
+	return e.ShutdownTimeout
+}
+
+// 从注册中心注销RegisterOption中的服务(设置了ServiceName且RegisterCli实现了register.Deregisterer时)
+func (e *Engine) deregister() error {
+	if e.RegisterCli == nil || e.RegisterOption.ServiceName == "" {
+		return nil
+	}
+	deregisterer, ok := e.RegisterCli.(register.Deregisterer)
+	if !ok {
+		return nil
+	}
+	return deregisterer.DeregisterService(e.RegisterOption.ServiceName, e.RegisterOption.Host, e.RegisterOption.Port)
+}
+
+// 根据RegisterType创建注册中心客户端
+func (e *Engine) createRegisterCli() error {
+	if e.RegisterCli != nil {
+		return nil
+	}
+	var cli register.QueenRegister
+	switch e.RegisterType {
+	case "nacos":
+		cli = &register.QueenNacosRegister{}
+	case "etcd":
+		cli = &register.QueenEtcdRegister{}
+	default:
+		return nil
+	}
+	if err := cli.CreateCli(e.RegisterOption); err != nil {
+		return err
+	}
+	e.RegisterCli = cli
+	e.ownRegisterCli = true
+	return nil
+}
@@ -0,0 +1,197 @@
+package qiaomu
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qingbo1011/qiaomu/register"
+)
+
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestRunContextGracefulShutdown(t *testing.T) {
+	var trace []string
+	engine := New()
+	engine.OnStart(func(ctx context.Context) error {
+		trace = append(trace, "start")
+		return nil
+	})
+	engine.OnStop(func(ctx context.Context) error {
+		trace = append(trace, "stop1")
+		return nil
+	}, func(ctx context.Context) error {
+		trace = append(trace, "stop2")
+		return nil
+	})
+	started := make(chan struct{})
+	engine.Group("user").Get("/slow", func(ctx *Context) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		ctx.String(http.StatusOK, "done")
+	})
+
+	addr := freeAddr(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- engine.RunContext(ctx, addr)
+	}()
+
+	for i := 0; i < 50; i++ {
+		if conn, dialErr := net.Dial("tcp", addr); dialErr == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	respChan := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/user/slow")
+		if err != nil {
+			respChan <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		respChan <- string(body)
+	}()
+	<-started
+	cancel()
+
+	if body := <-respChan; body != "done" {
+		t.Errorf("in-flight request got %q, want done", body)
+	}
+	if err := <-runErr; err != nil {
+		t.Errorf("RunContext returned %v", err)
+	}
+	if got := strings.Join(trace, ","); got != "start,stop2,stop1" {
+		t.Errorf("got %s, want start,stop2,stop1", got)
+	}
+}
+
+func TestRunListensRedirectToHTTPS(t *testing.T) {
+	engine := New()
+	engine.Server.ReadHeaderTimeout = time.Second
+	engine.Group("user").Get("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "info")
+	})
+	httpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpsListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, httpsPort, _ := net.SplitHostPort(httpsListener.Addr().String())
+	// 借用httptest自带的测试证书
+	ts := httptest.NewUnstartedServer(nil)
+	ts.StartTLS()
+	tlsClient := ts.Client()
+	engine.Server.TLSConfig = &tls.Config{Certificates: ts.TLS.Certificates}
+	ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- engine.RunListens(ctx,
+			ListenConfig{Listener: httpListener, RedirectToHTTPS: true},
+			ListenConfig{Listener: httpsListener, TLS: true},
+		)
+	}()
+
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get("http://" + httpListener.Addr().String() + "/user/info?id=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	want := "https://127.0.0.1:" + httpsPort + "/user/info?id=1"
+	if resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != want {
+		t.Errorf("got %d %s, want %d %s", resp.StatusCode, resp.Header.Get("Location"), http.StatusMovedPermanently, want)
+	}
+	resp, err = tlsClient.Get(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "info" {
+		t.Errorf("https got %q, want info", body)
+	}
+	cancel()
+	<-runErr
+}
+
+// 只实现了register.QueenRegister的注册中心客户端
+type testRegister struct {
+	registered map[string]string
+}
+
+func (r *testRegister) CreateCli(option register.Option) error { return nil }
+
+func (r *testRegister) RegisterService(serviceName string, host string, port int) error {
+	r.registered[serviceName] = fmt.Sprintf("%s:%d", host, port)
+	return nil
+}
+
+func (r *testRegister) GetValue(serviceName string) (string, error) {
+	return r.registered[serviceName], nil
+}
+
+func (r *testRegister) Close() error { return nil }
+
+type testDeregisterer struct {
+	testRegister
+}
+
+func (r *testDeregisterer) DeregisterService(serviceName string, host string, port int) error {
+	delete(r.registered, serviceName)
+	return nil
+}
+
+func TestShutdownDeregister(t *testing.T) {
+	option := register.Option{ServiceName: "goods", Host: "127.0.0.1", Port: 8082}
+
+	cli := &testDeregisterer{testRegister{registered: map[string]string{}}}
+	cli.RegisterService(option.ServiceName, option.Host, option.Port)
+	engine := New()
+	engine.RegisterCli = cli
+	engine.RegisterOption = option
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cli.registered["goods"]; ok {
+		t.Error("service should be deregistered on shutdown")
+	}
+
+	// 没有实现register.Deregisterer的客户端不注销
+	plain := &testRegister{registered: map[string]string{}}
+	plain.RegisterService(option.ServiceName, option.Host, option.Port)
+	engine = New()
+	engine.RegisterCli = plain
+	engine.RegisterOption = option
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := plain.registered["goods"]; !ok {
+		t.Error("service should stay registered")
+	}
+}
@@ -0,0 +1,88 @@
+package qiaomu
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/qingbo1011/qiaomu/render"
+)
+
+// SSEvent 发送一个Server-Sent Events事件并立即flush(第一次调用时设置text/event-stream等响应头)
+func (c *Context) SSEvent(event string, data any) error {
+	return c.Render(http.StatusOK, &render.SSEvent{Event: event, Data: data})
+}
+
+// SSEComment 发送SSE注释(客户端会忽略)，用作心跳防止连接被代理断开
+func (c *Context) SSEComment(comment string) error {
+	(&render.SSEvent{}).WriteContentType(c.W)
+	if _, err := io.WriteString(c.W, ": "+strings.NewReplacer("\n", " ", "\r", " ").Replace(comment)+"\n\n"); err != nil {
+		return err
+	}
+	c.Flush()
+	return nil
+}
+
+// LastEventID 客户端断线重连时带上的最后收到的事件id(请求头Last-Event-ID)，用于从断点继续推送
+func (c *Context) LastEventID() string {
+	return c.R.Header.Get("Last-Event-ID")
+}
+
+// Flush 将缓冲的响应数据立即发送给客户端
+func (c *Context) Flush() {
+	if f, ok := c.W.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Stream 持续推送数据：每次调用step后自动flush，step返回false时结束
+// 客户端断开连接时不再调用step并返回true
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	done := c.R.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		default:
+			keepOpen := step(c.W)
+			c.Flush()
+			if !keepOpen {
+				return false
+			}
+		}
+	}
+}
+
+// SSEStream 依次发送events中的事件，heartbeat大于0时每隔heartbeat发送一次心跳注释
+// events被关闭时返回nil，客户端断开连接时返回R.Context().Err()
+func (c *Context) SSEStream(events <-chan render.SSEvent, heartbeat time.Duration) error {
+	var tick <-chan time.Time
+	if heartbeat > 0 {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	// 先返回响应头，客户端可以立即知道连接已经建立
+	(&render.SSEvent{}).WriteContentType(c.W)
+	c.W.WriteHeader(http.StatusOK)
+	c.Flush()
+	done := c.R.Context().Done()
+	for {
+		select {
+		case <-done:
+			return c.R.Context().Err()
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := event.Render(c.W, http.StatusOK); err != nil {
+				return err
+			}
+		case <-tick:
+			if err := c.SSEComment("heartbeat"); err != nil {
+				return err
+			}
+		}
+	}
+}
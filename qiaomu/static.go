@@ -0,0 +1,152 @@
+package qiaomu
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// StaticConfig 静态文件服务配置
+type StaticConfig struct {
+	Index        string // 目录的默认文件，默认为index.html
+	Browse       bool   // 目录下没有Index文件时是否列出目录内容
+	SPA          bool   // 单页应用模式：文件不存在时返回根目录下的Index文件(前端路由由页面自己处理)
+	CacheControl string // 响应头Cache-Control的值，如 public, max-age=3600
+	ETag         bool   // 是否根据文件的修改时间和大小生成ETag(配合If-None-Match返回304)
+}
+
+// Static 将本地目录dir映射到prefix下(如Static("/assets", "./public")，请求/assets/js/app.js返回./public/js/app.js)
+func (r *routerGroup) Static(prefix, dir string, config ...StaticConfig) {
+	r.StaticFS(prefix, http.Dir(dir), config...)
+}
+
+// StaticFS 将文件系统fs映射到prefix下，embed.FS可以通过http.FS转换后使用
+// (如StaticFS("/admin", http.FS(sub))，sub为fs.Sub(embedFS, "dist")的结果)
+func (r *routerGroup) StaticFS(prefix string, fs http.FileSystem, config ...StaticConfig) {
+	if strings.Contains(prefix, ":") || strings.Contains(prefix, "*") {
+		panic(errors.New(fmt.Sprintf("static prefix [%s] can not contain params or wildcards", prefix)))
+	}
+	handler := newStaticHandler(fs, config...)
+	r.Get(joinPaths(strings.TrimSuffix(prefix, "/"), "/**"), handler.serve)
+}
+
+// StaticFile 将单个本地文件映射到指定路由上(如StaticFile("/favicon.ico", "./resources/favicon.ico"))
+func (r *routerGroup) StaticFile(name, file string, config ...StaticConfig) {
+	dir, fileName := path.Split(file)
+	handler := newStaticHandler(http.Dir(dir), config...)
+	r.Get(name, func(ctx *Context) {
+		handler.serveFile(ctx, "/"+fileName)
+	})
+}
+
+type staticHandler struct {
+	fs     http.FileSystem
+	config StaticConfig
+}
+
+func newStaticHandler(fs http.FileSystem, config ...StaticConfig) *staticHandler {
+	h := &staticHandler{fs: fs}
+	if len(config) > 0 {
+		h.config = config[0]
+	}
+	if h.config.Index == "" {
+		h.config.Index = "index.html"
+	}
+	return h
+}
+
+func (h *staticHandler) serve(ctx *Context) {
+	name := ctx.Param("**")
+	// 防止目录穿越(../../etc/passwd)
+	if containsDotDot(name) || strings.Contains(name, "\x00") {
+		ctx.String(http.StatusBadRequest, "invalid path\n")
+		return
+	}
+	h.serveFile(ctx, path.Clean("/"+name))
+}
+
+func (h *staticHandler) serveFile(ctx *Context, name string) {
+	f, err := h.fs.Open(name)
+	if err != nil {
+		h.notFound(ctx)
+		return
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		h.notFound(ctx)
+		return
+	}
+	if stat.IsDir() {
+		// 目录需要以/结尾，否则页面中的相对路径会解析错误
+		if !strings.HasSuffix(ctx.R.URL.Path, "/") {
+			target := path.Base(ctx.R.URL.Path) + "/"
+			if ctx.R.URL.RawQuery != "" {
+				target += "?" + ctx.R.URL.RawQuery
+			}
+			ctx.W.Header().Set("Location", target)
+			ctx.W.WriteHeader(http.StatusMovedPermanently)
+			return
+		}
+		index, err := h.fs.Open(path.Join(name, h.config.Index))
+		if err == nil {
+			defer index.Close()
+			if indexStat, err := index.Stat(); err == nil && !indexStat.IsDir() {
+				h.serveContent(ctx, index, indexStat)
+				return
+			}
+		}
+		if h.config.Browse {
+			h.setCacheHeaders(ctx, nil)
+			ctx.FileFromFS(strings.TrimSuffix(name, "/")+"/", h.fs)
+			return
+		}
+		h.notFound(ctx)
+		return
+	}
+	h.serveContent(ctx, f, stat)
+}
+
+func (h *staticHandler) serveContent(ctx *Context, f http.File, stat os.FileInfo) {
+	h.setCacheHeaders(ctx, stat)
+	http.ServeContent(ctx.W, ctx.R, stat.Name(), stat.ModTime(), f)
+}
+
+func (h *staticHandler) setCacheHeaders(ctx *Context, stat os.FileInfo) {
+	if h.config.CacheControl != "" {
+		ctx.W.Header().Set("Cache-Control", h.config.CacheControl)
+	}
+	if h.config.ETag && stat != nil {
+		ctx.W.Header().Set("ETag", fmt.Sprintf(`W/"%x-%x"`, stat.ModTime().UnixNano(), stat.Size()))
+	}
+}
+
+// 文件不存在：SPA模式返回根目录下的Index文件，否则返回404
+func (h *staticHandler) notFound(ctx *Context) {
+	if h.config.SPA {
+		if f, err := h.fs.Open("/" + h.config.Index); err == nil {
+			defer f.Close()
+			if stat, err := f.Stat(); err == nil && !stat.IsDir() {
+				h.serveContent(ctx, f, stat)
+				return
+			}
+		}
+	}
+	notFoundHandler(ctx)
+}
+
+// 路径中是否有..片段
+func containsDotDot(p string) bool {
+	if !strings.Contains(p, "..") {
+		return false
+	}
+	for _, segment := range strings.FieldsFunc(p, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if segment == ".." {
+			return true
+		}
+	}
+	return false
+}
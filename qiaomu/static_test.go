@@ -0,0 +1,66 @@
+package qiaomu
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestStaticFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":       {Data: []byte("index"), ModTime: time.Now()},
+		"js/app.js":        {Data: []byte("app"), ModTime: time.Now()},
+		"docs/readme.txt":  {Data: []byte("readme"), ModTime: time.Now()},
+		"empty/.gitignore": {Data: []byte(""), ModTime: time.Now()},
+	}
+	engine := New()
+	group := engine.Group("web")
+	group.StaticFS("/assets", http.FS(fsys), StaticConfig{CacheControl: "public, max-age=60", ETag: true, Browse: true})
+	group.StaticFS("/app", http.FS(fsys), StaticConfig{SPA: true})
+
+	var testcases = []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/web/assets/js/app.js", http.StatusOK, "app"},
+		{"/web/assets/", http.StatusOK, "index"},
+		{"/web/assets/docs", http.StatusMovedPermanently, ""},
+		{"/web/assets/docs/", http.StatusOK, "readme.txt"},
+		{"/web/assets/missing.js", http.StatusNotFound, ""},
+		{"/web/assets/../index.html", http.StatusBadRequest, ""},
+		{"/web/assets/js/..%2f..%2findex.html", http.StatusBadRequest, ""},
+		{"/web/app/user/1", http.StatusOK, "index"},
+	}
+	for _, testcase := range testcases {
+		w := performRequest(engine, http.MethodGet, testcase.path)
+		if w.Code != testcase.code {
+			t.Errorf("%s: got status %d, want %d", testcase.path, w.Code, testcase.code)
+		}
+		if testcase.body != "" && !strings.Contains(w.Body.String(), testcase.body) {
+			t.Errorf("%s: got body %q, want %q", testcase.path, w.Body.String(), testcase.body)
+		}
+	}
+
+	w := performRequest(engine, http.MethodGet, "/web/assets/js/app.js")
+	if w.Header().Get("Cache-Control") != "public, max-age=60" {
+		t.Errorf("got Cache-Control %q", w.Header().Get("Cache-Control"))
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("ETag should be set")
+	}
+	req := httptest.NewRequest(http.MethodGet, "/web/assets/js/app.js", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: got status %d, want %d", w.Code, http.StatusNotModified)
+	}
+	if w := performRequest(engine, http.MethodHead, "/web/assets/js/app.js"); w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Errorf("HEAD: got %d %q", w.Code, w.Body.String())
+	}
+}
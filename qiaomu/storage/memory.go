@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"sync"
+	"time"
+)
+
+// Memory 内存存储(用于测试和单机开发)
+type Memory struct {
+	Signer  *Signer // 用于生成访问URL(为nil时URL返回ErrURLNotSupported)
+	mu      sync.RWMutex
+	objects map[string]*memoryObject
+}
+
+type memoryObject struct {
+	data []byte
+	info ObjectInfo
+}
+
+// NewMemory 创建内存存储
+func NewMemory() *Memory {
+	return &Memory{objects: make(map[string]*memoryObject)}
+}
+
+func (m *Memory) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (*ObjectInfo, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(contextReader{ctx: ctx, r: r})
+	if err != nil {
+		return nil, err
+	}
+	sum := md5.Sum(data)
+	object := &memoryObject{data: data, info: ObjectInfo{
+		Key:          key,
+		Size:         int64(len(data)),
+		ContentType:  contentType(key, opts.ContentType),
+		ETag:         `"` + hex.EncodeToString(sum[:]) + `"`,
+		LastModified: time.Now(),
+	}}
+	m.mu.Lock()
+	m.objects[key] = object
+	m.mu.Unlock()
+	info := object.info
+	return &info, nil
+}
+
+func (m *Memory) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	object, err := m.object(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	info := object.info
+	return readSeekNopCloser{bytes.NewReader(object.data)}, &info, nil
+}
+
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	delete(m.objects, key)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Memory) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	object, err := m.object(key)
+	if err != nil {
+		return nil, err
+	}
+	info := object.info
+	return &info, nil
+}
+
+func (m *Memory) URL(ctx context.Context, key string, expires time.Duration) (string, error) {
+	if m.Signer == nil {
+		return "", ErrURLNotSupported
+	}
+	return m.Signer.Sign(key, expires)
+}
+
+func (m *Memory) object(key string) (*memoryObject, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	object, ok := m.objects[key]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotExist
+	}
+	return object, nil
+}
+
+// 支持Seek的io.ReadCloser(Handler可以据此处理Range请求)
+type readSeekNopCloser struct {
+	*bytes.Reader
+}
+
+func (readSeekNopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotExist 对象不存在
+	ErrNotExist = errors.New("storage: object does not exist")
+	// ErrInvalidKey 对象键为空、是绝对路径或包含..等非法路径
+	ErrInvalidKey = errors.New("storage: invalid object key")
+	// ErrURLNotSupported 没有配置Signer，无法生成访问URL
+	ErrURLNotSupported = errors.New("storage: url signing is not configured")
+)
+
+// Storage 文件存储：以key(如images/2024/avatar.png，使用/分隔)为键存取对象，可以被多个goroutine并发使用
+type Storage interface {
+	// Put 写入对象(已存在时覆盖)
+	Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (*ObjectInfo, error)
+	// Get 读取对象，调用方负责关闭返回的io.ReadCloser
+	Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error)
+	// Delete 删除对象(不存在时不返回错误)
+	Delete(ctx context.Context, key string) error
+	// Stat 获取对象信息，不存在时返回ErrNotExist
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// URL 生成在expires时间内有效的对象访问URL
+	URL(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// PutOptions 写入对象的选项
+type PutOptions struct {
+	Size        int64  // 对象大小(<=0时表示未知，部分实现需要先读取全部数据)
+	ContentType string // 对象的Content-Type(为空时根据key的扩展名判断)
+}
+
+// ObjectInfo 对象信息
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// CleanKey 检查并规范化对象键：去掉开头的/，拒绝空键和包含..、\或NUL的键
+func CleanKey(key string) (string, error) {
+	key = strings.TrimLeft(key, "/")
+	if key == "" || strings.ContainsAny(key, "\\\x00") {
+		return "", ErrInvalidKey
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." || segment == "." {
+			return "", ErrInvalidKey
+		}
+	}
+	if key != path.Clean(key) {
+		return "", ErrInvalidKey
+	}
+	return key, nil
+}
+
+// Signer 为Local、Memory等没有原生访问URL的存储生成带过期时间和签名的URL，配合Handler提供下载
+type Signer struct {
+	BaseURL string // URL前缀(Handler挂载的地址)，如https://static.example.com/files
+	Secret  []byte // 签名密钥
+	now     func() time.Time
+}
+
+// Sign 生成key在expires时间内有效的URL：BaseURL/key?expires=过期时间戳&signature=签名
+func (s *Signer) Sign(key string, expires time.Duration) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	deadline := s.time().Add(expires).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(deadline, 10))
+	query.Set("signature", s.signature(key, deadline))
+	return strings.TrimRight(s.BaseURL, "/") + "/" + escapeKey(key) + "?" + query.Encode(), nil
+}
+
+// Verify 校验签名和过期时间
+func (s *Signer) Verify(key string, query url.Values) bool {
+	deadline, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || s.time().Unix() > deadline {
+		return false
+	}
+	return hmac.Equal([]byte(query.Get("signature")), []byte(s.signature(key, deadline)))
+}
+
+func (s *Signer) signature(key string, deadline int64) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(fmt.Sprintf("%s\n%d", key, deadline)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Signer) time() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// Handler 提供Signer生成的URL的下载服务(挂载在Signer.BaseURL对应的路径下，需要用http.StripPrefix去掉前缀)，
+// 签名无效或过期时返回403，对象不存在时返回404
+func Handler(s Storage, signer *Signer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		key, err := CleanKey(r.URL.Path)
+		if err != nil || !signer.Verify(key, r.URL.Query()) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		body, info, err := s.Get(r.Context(), key)
+		if errors.Is(err, ErrNotExist) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		defer body.Close()
+		if info.ContentType != "" {
+			w.Header().Set("Content-Type", info.ContentType)
+		}
+		if info.ETag != "" {
+			w.Header().Set("ETag", info.ETag)
+		}
+		if seeker, ok := body.(io.ReadSeeker); ok {
+			http.ServeContent(w, r, path.Base(key), info.LastModified, seeker)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+		if r.Method == http.MethodGet {
+			io.Copy(w, body)
+		}
+	})
+}
+
+// 按/分段转义对象键
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// 根据key的扩展名判断Content-Type
+func contentType(key, ct string) string {
+	if ct != "" {
+		return ct
+	}
+	if ct = mime.TypeByExtension(path.Ext(key)); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,197 @@
+package qiaomu
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var _ context.Context = (*Context)(nil)
+
+// Deadline 请求context的截止时间(见Timeout中间件)，Context实现了context.Context，可以直接传给orm、rpc等下游调用
+// 请求处理完成后Context会被复用，不应在处理函数返回后继续使用
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	if c.R == nil {
+		return
+	}
+	return c.R.Context().Deadline()
+}
+
+// Done 请求被取消(客户端断开连接、超时)时关闭的channel
+func (c *Context) Done() <-chan struct{} {
+	if c.R == nil {
+		return nil
+	}
+	return c.R.Context().Done()
+}
+
+// Err 请求被取消的原因(context.Canceled或context.DeadlineExceeded)，未被取消时为nil
+func (c *Context) Err() error {
+	if c.R == nil {
+		return nil
+	}
+	return c.R.Context().Err()
+}
+
+// Value 获取key对应的值：key为字符串时优先从Keys中获取(见Set)，否则从请求的context中获取
+func (c *Context) Value(key any) any {
+	if k, ok := key.(string); ok {
+		if value, exists := c.Get(k); exists {
+			return value
+		}
+	}
+	if c.R == nil {
+		return nil
+	}
+	return c.R.Context().Value(key)
+}
+
+// Timeout 超时中间件(按路由或路由组使用)：为请求的context设置超时时间，超时后取消context并返回503
+// 处理函数在独立的goroutine中执行，响应先写入缓冲区，超时后立即发送503，处理函数写入的响应会被丢弃(Write返回http.ErrHandlerTimeout)；
+// 处理函数应将ctx传给orm(WithContext)、rpc等下游调用，使其在超时后及时返回
+func Timeout(timeout time.Duration) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			reqCtx, cancel := context.WithTimeout(ctx.R.Context(), timeout)
+			defer cancel()
+			r, w := ctx.R, ctx.W
+			tw := &timeoutWriter{header: w.Header().Clone()}
+			ctx.R = r.WithContext(reqCtx)
+			ctx.W = tw
+
+			done := make(chan struct{})
+			var panicValue any
+			go func() {
+				defer func() {
+					panicValue = recover()
+					close(done)
+				}()
+				next(ctx)
+			}()
+			select {
+			case <-done:
+			case <-reqCtx.Done():
+				tw.discard()
+				if reqCtx.Err() == context.DeadlineExceeded {
+					body := http.StatusText(http.StatusServiceUnavailable)
+					w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+					// 带上Content-Length并立即发送，客户端不需要等处理函数返回就能读完响应
+					w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+					w.WriteHeader(http.StatusServiceUnavailable)
+					w.Write([]byte(body))
+					w.Flush()
+				}
+				// 等待处理函数返回后再归还Context
+				<-done
+			}
+			ctx.R, ctx.W = r, w
+			if panicValue != nil {
+				panic(panicValue)
+			}
+			tw.flushTo(w)
+		}
+	}
+}
+
+// 超时中间件使用的ResponseWriter：响应写入缓冲区，处理函数正常返回后再写入原ResponseWriter
+type timeoutWriter struct {
+	mu        sync.Mutex
+	header    http.Header
+	buf       bytes.Buffer
+	status    int
+	written   bool
+	discarded bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.written || w.discarded || code <= 0 {
+		return
+	}
+	w.status = code
+	w.written = true
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.discarded {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !w.written {
+		w.status = http.StatusOK
+		w.written = true
+	}
+	return w.buf.Write(data)
+}
+
+// Flush 响应需要等处理函数返回后才能发送，忽略
+func (w *timeoutWriter) Flush() {}
+
+func (w *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("qiaomu: hijack is not supported by the Timeout middleware")
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Len()
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.written
+}
+
+func (w *timeoutWriter) Pusher() http.Pusher {
+	return nil
+}
+
+func (w *timeoutWriter) discard() {
+	w.mu.Lock()
+	w.discarded = true
+	w.mu.Unlock()
+}
+
+// 将缓冲的响应头和响应体写入原ResponseWriter(已超时时忽略)
+func (w *timeoutWriter) flushTo(dst ResponseWriter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.discarded {
+		return
+	}
+	header := dst.Header()
+	for key := range header {
+		if _, ok := w.header[key]; !ok {
+			header.Del(key)
+		}
+	}
+	for key, values := range w.header {
+		header[key] = values
+	}
+	if w.written {
+		dst.WriteHeader(w.status)
+		dst.Write(w.buf.Bytes())
+	}
+}
@@ -0,0 +1,100 @@
+package qiaomu
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/qingbo1011/qiaomu/rpc"
+)
+
+type ctxKey struct{}
+
+func TestContextAsContext(t *testing.T) {
+	engine := New()
+	engine.Group("user").Get("/info", func(ctx *Context) {
+		ctx.Set("user", "qiaomu")
+		var c context.Context = ctx
+		if c.Value("user") != "qiaomu" || c.Value(ctxKey{}) != "trace" {
+			t.Errorf("got user %v, trace %v", c.Value("user"), c.Value(ctxKey{}))
+		}
+		if _, ok := c.Deadline(); ok || c.Err() != nil {
+			t.Error("want no deadline")
+		}
+		ctx.String(http.StatusOK, "ok")
+	})
+	r := httptest.NewRequest(http.MethodGet, "/user/info", nil)
+	r = r.WithContext(context.WithValue(r.Context(), ctxKey{}, "trace"))
+	engine.ServeHTTP(httptest.NewRecorder(), r)
+}
+
+func TestTimeout(t *testing.T) {
+	// 下游服务：响应很慢
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer downstream.Close()
+
+	engine := New()
+	g := engine.Group("order")
+	g.Get("/slow", func(ctx *Context) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("want deadline")
+		}
+		_, err := rpc.NewHttpClient().Session().WithContext(ctx).Get(downstream.URL, nil)
+		if err == nil || ctx.Err() != context.DeadlineExceeded {
+			t.Errorf("got err %v, ctx err %v", err, ctx.Err())
+		}
+		ctx.String(http.StatusOK, "too late")
+	}, Timeout(50*time.Millisecond))
+	g.Get("/fast", func(ctx *Context) {
+		ctx.W.Header().Set("X-Order", "1")
+		ctx.String(http.StatusCreated, "created")
+	}, Timeout(time.Second))
+
+	start := time.Now()
+	w := performRequest(engine, http.MethodGet, "/order/slow")
+	if w.Code != http.StatusServiceUnavailable || w.Body.String() != "Service Unavailable" {
+		t.Errorf("got %d %q", w.Code, w.Body.String())
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("downstream call was not cancelled, took %v", time.Since(start))
+	}
+
+	w = performRequest(engine, http.MethodGet, "/order/fast")
+	if w.Code != http.StatusCreated || w.Body.String() != "created" || w.Header().Get("X-Order") != "1" {
+		t.Errorf("got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+}
+
+func TestTimeoutRespondsBeforeHandlerReturns(t *testing.T) {
+	engine := New()
+	engine.Group("order").Get("/stuck", func(ctx *Context) {
+		// 忽略ctx.Done()的处理函数
+		time.Sleep(500 * time.Millisecond)
+		ctx.String(http.StatusOK, "too late")
+	}, Timeout(50*time.Millisecond))
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	start := time.Now()
+	resp, err := http.Get(server.URL + "/order/stuck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	elapsed := time.Since(start)
+	if err != nil || resp.StatusCode != http.StatusServiceUnavailable || string(body) != "Service Unavailable" {
+		t.Errorf("got %d %q %v", resp.StatusCode, body, err)
+	}
+	if elapsed > 300*time.Millisecond {
+		t.Errorf("503 arrived after %v, want near the 50ms timeout", elapsed)
+	}
+}
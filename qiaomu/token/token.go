@@ -234,11 +234,12 @@ func (j *JwtHandler) AuthInterceptor(next qiaomu.HandlerFunc) qiaomu.HandlerFunc
 	}
 }
 
-// AuthErrorHandler 认证错误处理
+// AuthErrorHandler 认证错误处理(终止处理链)
 func (j *JwtHandler) AuthErrorHandler(ctx *qiaomu.Context, err error) {
 	if j.AuthHandler == nil {
-		ctx.W.WriteHeader(http.StatusUnauthorized)
+		ctx.AbortWithStatus(http.StatusUnauthorized)
 	} else {
+		ctx.Abort()
 		j.AuthHandler(ctx, err)
 	}
 }
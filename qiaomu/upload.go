@@ -0,0 +1,131 @@
+package qiaomu
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/qingbo1011/qiaomu/storage"
+)
+
+// 文件名最大字节数(大多数文件系统的限制)
+const maxFileNameLength = 255
+
+// 解析multipart表单时保存在内存中的最大字节数
+func (c *Context) multipartMemory() int64 {
+	if c.engine != nil && c.engine.MaxMultipartMemory > 0 {
+		return c.engine.MaxMultipartMemory
+	}
+	return defaultMultipartMemory
+}
+
+// MultipartReader 以流的方式读取multipart/form-data请求体(不会缓存到内存或临时文件)，
+// 与FormFile、PostForm、MultipartForm等方法互斥，只能使用其中一种方式读取请求体
+func (c *Context) MultipartReader() (*multipart.Reader, error) {
+	return c.R.MultipartReader()
+}
+
+// WalkMultipart 按顺序遍历multipart/form-data请求体中的每个部分，part在fn返回后失效，
+// 文件部分(part.FileName()不为空)可以直接读取并写入存储(如storage.Storage)，fn返回错误时停止遍历并返回该错误
+func (c *Context) WalkMultipart(fn func(part *multipart.Part) error) error {
+	reader, err := c.MultipartReader()
+	if err != nil {
+		return err
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = fn(part)
+		part.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// SaveUploadedFile 保存上传的文件，目录不存在时自动创建
+// dst为目录(以路径分隔符结尾或是已经存在的目录)时，文件保存为该目录下经过SanitizeFileName处理的原文件名，
+// 否则按dst原样保存(dst由调用方指定，不做处理)
+func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	dir, name := filepath.Split(dst)
+	if info, err := os.Stat(dst); name == "" || (err == nil && info.IsDir()) {
+		dir = dst
+		dst = filepath.Join(dir, SanitizeFileName(file.Filename))
+	}
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			return err
+		}
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, src)
+	return err
+}
+
+// SaveUploadedFileTo 将上传的文件写入存储(如storage.Local、storage.S3)，key以/结尾时使用经过SanitizeFileName处理的原文件名
+func (c *Context) SaveUploadedFileTo(file *multipart.FileHeader, s storage.Storage, key string) (*storage.ObjectInfo, error) {
+	if key == "" || strings.HasSuffix(key, "/") {
+		key += SanitizeFileName(file.Filename)
+	}
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	return s.Put(c, key, src, storage.PutOptions{Size: file.Size, ContentType: file.Header.Get("Content-Type")})
+}
+
+// SanitizeFileName 处理客户端提供的文件名：去掉路径部分(包括Windows路径)、控制字符和文件系统保留字符，
+// 去掉首尾的空格和点，限制长度，结果为空时返回"file"
+func SanitizeFileName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.Map(func(r rune) rune {
+		if r == utf8.RuneError || unicode.IsControl(r) || strings.ContainsRune(`<>:"|?*`, r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, " .")
+	if len(name) > maxFileNameLength {
+		// 保留扩展名，按字符截断
+		ext := filepath.Ext(name)
+		if len(ext) > maxFileNameLength/2 {
+			ext = ""
+		}
+		base := name[:len(name)-len(ext)]
+		for len(base)+len(ext) > maxFileNameLength {
+			_, size := utf8.DecodeLastRuneInString(base)
+			base = base[:len(base)-size]
+		}
+		name = base + ext
+	}
+	if name == "" {
+		return "file"
+	}
+	return name
+}
@@ -0,0 +1,159 @@
+package qiaomu
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/qingbo1011/qiaomu/storage"
+)
+
+func TestMaxBodyBytes(t *testing.T) {
+	engine := New()
+	engine.MaxBodyBytes = 16
+	g := engine.Group("goods")
+	handler := func(ctx *Context) {
+		var goods struct {
+			Name string `json:"name"`
+		}
+		if err := ctx.BindJson(&goods); err != nil {
+			return
+		}
+		ctx.String(http.StatusOK, "ok")
+	}
+	g.Post("/small", handler)
+	g.Post("/large", handler, MaxBodyBytes(1<<10))
+	g.Post("/read", func(ctx *Context) {
+		io.ReadAll(ctx.R.Body)
+	})
+
+	body := `{"name":"` + strings.Repeat("a", 100) + `"}`
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/goods/small", http.StatusRequestEntityTooLarge},
+		{"/goods/large", http.StatusOK},
+		{"/goods/read", http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		// 不带Content-Length时同样在读取过程中判断
+		r.ContentLength = -1
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.path, w.Code, tt.want)
+		}
+	}
+}
+
+func newMultipartRequest(t *testing.T, path string, files map[string]string) *http.Request {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("title", "goods")
+	for name, content := range files {
+		fw, err := mw.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	mw.Close()
+	r := httptest.NewRequest(http.MethodPost, path, &buf)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestWalkMultipart(t *testing.T) {
+	engine := New()
+	engine.Group("upload").Post("/stream", func(ctx *Context) {
+		var parts []string
+		err := ctx.WalkMultipart(func(part *multipart.Part) error {
+			data, err := io.ReadAll(part)
+			parts = append(parts, part.FormName()+":"+part.FileName()+":"+string(data))
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx.String(http.StatusOK, strings.Join(parts, ","))
+	})
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, newMultipartRequest(t, "/upload/stream", map[string]string{"a.png": "png"}))
+	if w.Body.String() != "title::goods,file:a.png:png" {
+		t.Errorf("got %q", w.Body.String())
+	}
+}
+
+func TestSaveUploadedFile(t *testing.T) {
+	dir := t.TempDir()
+	engine := New()
+	engine.Group("upload").Post("/save", func(ctx *Context) {
+		file := ctx.FormFiles("file")[0]
+		if err := ctx.SaveUploadedFile(file, filepath.Join(dir, "images", "2024")+"/"); err != nil {
+			t.Fatal(err)
+		}
+		// 调用方指定的文件名原样使用
+		if err := ctx.SaveUploadedFile(file, filepath.Join(dir, "named", "..avatar.png")); err != nil {
+			t.Fatal(err)
+		}
+	})
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, newMultipartRequest(t, "/upload/save", map[string]string{`..\..\secret<1>.png`: "png"}))
+	for _, name := range []string{filepath.Join("images", "2024", "secret1.png"), filepath.Join("named", "..avatar.png")} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(data) != "png" {
+			t.Errorf("%s: got %q, %v", name, data, err)
+		}
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := map[string]string{
+		"avatar.png":                      "avatar.png",
+		"../../etc/passwd":                "passwd",
+		`C:\Users\me\a b.jpg`:             "a b.jpg",
+		"..":                              "file",
+		"  .hidden. ":                     "hidden",
+		"a\x00b\nc.txt":                   "abc.txt",
+		strings.Repeat("x", 300) + ".jpg": strings.Repeat("x", 251) + ".jpg",
+	}
+	for name, want := range tests {
+		if got := SanitizeFileName(name); got != want {
+			t.Errorf("SanitizeFileName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestSaveUploadedFileTo(t *testing.T) {
+	s := storage.NewMemory()
+	engine := New()
+	engine.Group("upload").Post("/storage", func(ctx *Context) {
+		file := ctx.FormFiles("file")[0]
+		if _, err := ctx.SaveUploadedFileTo(file, s, "avatars/"); err != nil {
+			t.Fatal(err)
+		}
+		info, err := ctx.SaveUploadedFileTo(file, s, "goods/1.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx.String(http.StatusOK, "%s %d", info.Key, info.Size)
+	})
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, newMultipartRequest(t, "/upload/storage", map[string]string{"../me.png": "png"}))
+	if w.Body.String() != "goods/1.png 3" {
+		t.Errorf("got %q", w.Body.String())
+	}
+	if info, err := s.Stat(context.Background(), "avatars/me.png"); err != nil || info.Size != 3 {
+		t.Errorf("got %+v, %v", info, err)
+	}
+}
@@ -0,0 +1,89 @@
+package qiaomu
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// URL 根据路由名称生成url，params为键值对：与路由参数同名的值填充到路径中(*和**以"*"、"**"为键)，其余的作为查询参数
+// 如路由/user/:id命名为user.info，URL("user.info", "id", 1, "tab", "orders") 返回 /user/1?tab=orders
+func (e *Engine) URL(name string, params ...any) (string, error) {
+	rt, ok := e.namedRoutes[name]
+	if !ok {
+		return "", errors.New(fmt.Sprintf("route named [%s] is not exist", name))
+	}
+	if len(params)%2 != 0 {
+		return "", errors.New(fmt.Sprintf("params of route [%s] must be key-value pairs", name))
+	}
+	values := make(map[string]any, len(params)/2)
+	keys := make([]string, 0, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		key, ok := params[i].(string)
+		if !ok {
+			return "", errors.New(fmt.Sprintf("param key [%v] of route [%s] must be a string", params[i], name))
+		}
+		if _, exist := values[key]; !exist {
+			keys = append(keys, key)
+		}
+		values[key] = params[i+1]
+	}
+
+	tokens, err := tokenizeRoute(rt.fullPath)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	used := make(map[string]bool)
+	for _, token := range tokens {
+		if token.nType == static {
+			sb.WriteString(token.text)
+			continue
+		}
+		key := token.text
+		if token.nType == param {
+			key = token.name
+		}
+		value, ok := values[key]
+		if !ok {
+			return "", errors.New(fmt.Sprintf("param [%s] of route [%s] is required", key, name))
+		}
+		used[key] = true
+		s := fmt.Sprint(value)
+		if token.nType == catchAll {
+			// **匹配多段路径，逐段转义
+			segments := strings.Split(s, "/")
+			for i, segment := range segments {
+				segments[i] = url.PathEscape(segment)
+			}
+			sb.WriteString(strings.Join(segments, "/"))
+			continue
+		}
+		if s == "" {
+			return "", errors.New(fmt.Sprintf("param [%s] of route [%s] can not be empty", key, name))
+		}
+		if token.constraint != nil && !token.constraint.match(s) {
+			return "", errors.New(fmt.Sprintf("param [%s] of route [%s] does not match constraint [%s]", key, name, token.constraint))
+		}
+		sb.WriteString(url.PathEscape(s))
+	}
+
+	query := url.Values{}
+	for _, key := range keys {
+		if used[key] {
+			continue
+		}
+		switch value := values[key].(type) {
+		case []string:
+			query[key] = append(query[key], value...)
+		default:
+			query.Add(key, fmt.Sprint(value))
+		}
+	}
+	if len(query) > 0 {
+		sb.WriteByte('?')
+		sb.WriteString(query.Encode())
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,40 @@
+package qiaomu
+
+import (
+	"github.com/qingbo1011/qiaomu/websocket"
+)
+
+var defaultUpgrader = &websocket.Upgrader{}
+
+// WebSocketHandler WebSocket路由的处理函数，函数返回后连接会被关闭(ctx同样只在函数执行期间有效)
+type WebSocketHandler func(ctx *Context, conn *websocket.Conn)
+
+// Upgrade 将当前请求升级为WebSocket连接(通过Hijack接管底层连接)，upgrader为空时使用Engine.Upgrader
+// 握手失败时已经向客户端返回了对应的http错误
+func (c *Context) Upgrade(upgrader ...*websocket.Upgrader) (*websocket.Conn, error) {
+	u := defaultUpgrader
+	if len(upgrader) > 0 && upgrader[0] != nil {
+		u = upgrader[0]
+	} else if c.engine != nil && c.engine.Upgrader != nil {
+		u = c.engine.Upgrader
+	}
+	return u.Upgrade(c.W, c.R, nil)
+}
+
+// WebSocket 注册WebSocket路由(GET)：请求先经过路由组和路由的中间件(如token.JwtHandler.AuthInterceptor)，
+// 中间件全部通过后再升级为WebSocket连接并调用handler
+func (r *routerGroup) WebSocket(name string, handler WebSocketHandler, middlewareFunc ...MiddlewareFunc) *Route {
+	rt := r.Get(name, func(ctx *Context) {
+		conn, err := ctx.Upgrade()
+		if err != nil {
+			if _, ok := err.(websocket.HandshakeError); !ok && ctx.Logger != nil {
+				ctx.Logger.Error(err.Error())
+			}
+			return
+		}
+		defer conn.Close()
+		handler(ctx, conn)
+	}, middlewareFunc...)
+	rt.handler = nameOfFunction(handler)
+	return rt
+}
@@ -0,0 +1,476 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 消息类型(RFC 6455 opcode)
+const (
+	continuationFrame = 0
+	TextMessage       = 1
+	BinaryMessage     = 2
+	CloseMessage      = 8
+	PingMessage       = 9
+	PongMessage       = 10
+)
+
+// 关闭状态码
+const (
+	CloseNormalClosure       = 1000
+	CloseGoingAway           = 1001
+	CloseProtocolError       = 1002
+	CloseUnsupportedData     = 1003
+	CloseNoStatusReceived    = 1005
+	CloseInvalidFramePayload = 1007
+	ClosePolicyViolation     = 1008
+	CloseMessageTooBig       = 1009
+	CloseInternalServerError = 1011
+)
+
+const maxControlPayload = 125
+
+// DefaultReadLimit 单个消息默认的最大字节数
+const DefaultReadLimit = 32 << 20
+
+var (
+	ErrCloseSent = errors.New("websocket: close sent")
+	ErrReadLimit = errors.New("websocket: read limit exceeded")
+)
+
+// CloseError 收到对端的关闭帧时ReadMessage返回的错误
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Text)
+}
+
+// IsCloseError 判断err是否是状态码为codes之一的CloseError
+func IsCloseError(err error, codes ...int) bool {
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) {
+		return false
+	}
+	for _, code := range codes {
+		if closeErr.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// 对端违反协议时的错误
+type protocolError string
+
+func (e protocolError) Error() string {
+	return "websocket: " + string(e)
+}
+
+// FormatCloseMessage 生成关闭帧的内容
+func FormatCloseMessage(code int, text string) []byte {
+	if code == CloseNoStatusReceived {
+		return []byte{}
+	}
+	buf := make([]byte, 2+len(text))
+	binary.BigEndian.PutUint16(buf, uint16(code))
+	copy(buf[2:], text)
+	return buf
+}
+
+// Conn WebSocket连接
+// 同一时间只能有一个goroutine读，写操作(包括WriteControl)可以被多个goroutine并发调用
+type Conn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	bw          *bufio.Writer
+	isServer    bool
+	subprotocol string
+
+	writeMu       sync.Mutex
+	writeDeadline time.Time
+	closeSent     bool
+
+	readLimit    int64
+	readErr      error
+	pingHandler  func(appData string) error
+	pongHandler  func(appData string) error
+	closeHandler func(code int, text string) error
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, bw *bufio.Writer, isServer bool, subprotocol string) *Conn {
+	c := &Conn{
+		conn:        conn,
+		br:          br,
+		bw:          bw,
+		isServer:    isServer,
+		subprotocol: subprotocol,
+		readLimit:   DefaultReadLimit,
+		done:        make(chan struct{}),
+	}
+	c.SetPingHandler(nil)
+	c.SetPongHandler(nil)
+	c.SetCloseHandler(nil)
+	return c
+}
+
+// Subprotocol 协商后的子协议
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// LocalAddr 本地地址
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// RemoteAddr 对端地址
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// NetConn 底层的网络连接
+func (c *Conn) NetConn() net.Conn {
+	return c.conn
+}
+
+// Close 直接关闭底层连接(不发送关闭帧，需要正常关闭时先调用WriteControl发送CloseMessage)
+func (c *Conn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+		err = c.conn.Close()
+	})
+	return err
+}
+
+// SetReadDeadline 设置读超时时间，超时后连接不可再用
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline 设置写超时时间(对WriteMessage生效，WriteControl使用单独传入的deadline)
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.writeDeadline = t
+	return nil
+}
+
+// SetReadLimit 设置单个消息的最大字节数(默认DefaultReadLimit，小于等于0表示不限制)，超过时发送关闭帧(1009)并返回ErrReadLimit
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+// SetPingHandler 设置收到ping时的处理函数(为nil时回复pong)
+func (c *Conn) SetPingHandler(h func(appData string) error) {
+	if h == nil {
+		h = func(appData string) error {
+			err := c.WriteControl(PongMessage, []byte(appData), time.Now().Add(time.Second))
+			if err == ErrCloseSent {
+				return nil
+			}
+			if e, ok := err.(net.Error); ok && e.Timeout() {
+				return nil
+			}
+			return err
+		}
+	}
+	c.pingHandler = h
+}
+
+// SetPongHandler 设置收到pong时的处理函数(为nil时不做处理)
+func (c *Conn) SetPongHandler(h func(appData string) error) {
+	if h == nil {
+		h = func(string) error { return nil }
+	}
+	c.pongHandler = h
+}
+
+// SetCloseHandler 设置收到关闭帧时的处理函数(为nil时回复相同状态码的关闭帧)，之后ReadMessage返回*CloseError
+func (c *Conn) SetCloseHandler(h func(code int, text string) error) {
+	if h == nil {
+		h = func(code int, text string) error {
+			c.WriteControl(CloseMessage, FormatCloseMessage(code, ""), time.Now().Add(time.Second))
+			return nil
+		}
+	}
+	c.closeHandler = h
+}
+
+// Keepalive 每隔interval发送一次ping，并要求在wait时间内收到对端的pong，否则ReadMessage返回超时错误
+// 会覆盖SetPongHandler设置的处理函数，连接关闭后自动停止
+func (c *Conn) Keepalive(interval, wait time.Duration) {
+	c.SetReadDeadline(time.Now().Add(wait))
+	c.SetPongHandler(func(string) error {
+		return c.SetReadDeadline(time.Now().Add(wait))
+	})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.WriteControl(PingMessage, nil, time.Now().Add(wait)); err != nil {
+					return
+				}
+			case <-c.done:
+				return
+			}
+		}
+	}()
+}
+
+// ReadMessage 读取一个完整的消息(自动拼接分片，处理ping/pong/close控制帧)，messageType为TextMessage或BinaryMessage
+func (c *Conn) ReadMessage() (messageType int, p []byte, err error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+	defer func() {
+		if err != nil {
+			c.readErr = err
+		}
+	}()
+	for {
+		fin, opcode, payload, err := c.readFrame(int64(len(p)))
+		if err != nil {
+			return 0, nil, c.handleReadError(err)
+		}
+		switch opcode {
+		case PingMessage:
+			if err := c.pingHandler(string(payload)); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if err := c.pongHandler(string(payload)); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case CloseMessage:
+			closeErr := &CloseError{Code: CloseNoStatusReceived}
+			if len(payload) == 1 {
+				return 0, nil, c.handleReadError(protocolError("invalid close payload"))
+			}
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Text = string(payload[2:])
+			}
+			if err := c.closeHandler(closeErr.Code, closeErr.Text); err != nil {
+				return 0, nil, err
+			}
+			return 0, nil, closeErr
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, c.handleReadError(protocolError("data frame received before previous message finished"))
+			}
+			messageType = opcode
+			p = payload
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, c.handleReadError(protocolError("continuation frame without a started message"))
+			}
+			p = append(p, payload...)
+		default:
+			return 0, nil, c.handleReadError(protocolError(fmt.Sprintf("unknown opcode %d", opcode)))
+		}
+		if fin {
+			if messageType == TextMessage && !utf8.Valid(p) {
+				c.WriteControl(CloseMessage, FormatCloseMessage(CloseInvalidFramePayload, "invalid utf8"), time.Now().Add(time.Second))
+				return 0, nil, protocolError("invalid utf8 in text message")
+			}
+			return messageType, p, nil
+		}
+	}
+}
+
+// 读取失败时按错误类型向对端发送关闭帧
+func (c *Conn) handleReadError(err error) error {
+	if _, ok := err.(protocolError); ok {
+		c.WriteControl(CloseMessage, FormatCloseMessage(CloseProtocolError, ""), time.Now().Add(time.Second))
+	}
+	if err == ErrReadLimit {
+		c.WriteControl(CloseMessage, FormatCloseMessage(CloseMessageTooBig, ""), time.Now().Add(time.Second))
+	}
+	return err
+}
+
+// 读取一帧，read为当前消息已读取的字节数(用于检查readLimit)
+func (c *Conn) readFrame(read int64) (fin bool, opcode int, payload []byte, err error) {
+	var header [8]byte
+	if _, err = io.ReadFull(c.br, header[:2]); err != nil {
+		return
+	}
+	fin = header[0]&0x80 != 0
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, protocolError("reserved bits are set")
+	}
+	opcode = int(header[0] & 0x0f)
+	masked := header[1]&0x80 != 0
+	length := int64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		if _, err = io.ReadFull(c.br, header[:2]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint16(header[:2]))
+	case 127:
+		if _, err = io.ReadFull(c.br, header[:8]); err != nil {
+			return
+		}
+		if header[0]&0x80 != 0 {
+			return false, 0, nil, protocolError("invalid payload length")
+		}
+		length = int64(binary.BigEndian.Uint64(header[:8]))
+	}
+	// 客户端发送的帧必须掩码，服务端发送的帧不能掩码
+	if masked != c.isServer {
+		return false, 0, nil, protocolError("incorrect mask flag")
+	}
+	if opcode >= CloseMessage && (!fin || length > maxControlPayload) {
+		return false, 0, nil, protocolError("invalid control frame")
+	}
+	var key [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.br, key[:]); err != nil {
+			return
+		}
+	}
+	if opcode < CloseMessage && c.readLimit > 0 && read+length > c.readLimit {
+		return false, 0, nil, ErrReadLimit
+	}
+	// 按实际收到的数据增长缓冲区，不按对端声明的长度预先分配
+	var buf bytes.Buffer
+	if _, err = io.CopyN(&buf, c.br, length); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+	payload = buf.Bytes()
+	if masked {
+		maskBytes(key, payload)
+	}
+	return fin, opcode, payload, nil
+}
+
+// WriteMessage 发送一个消息，messageType为TextMessage或BinaryMessage
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return errors.New(fmt.Sprintf("websocket: invalid message type %d", messageType))
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.writeFrame(messageType, data, c.writeDeadline)
+}
+
+// 使用指定的deadline发送消息(不影响SetWriteDeadline设置的值)
+func (c *Conn) writeMessageWithDeadline(messageType int, data []byte, deadline time.Time) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.writeFrame(messageType, data, deadline)
+}
+
+// WriteControl 发送控制帧(CloseMessage、PingMessage、PongMessage)，data不能超过125字节
+func (c *Conn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if messageType != CloseMessage && messageType != PingMessage && messageType != PongMessage {
+		return errors.New(fmt.Sprintf("websocket: invalid control message type %d", messageType))
+	}
+	if len(data) > maxControlPayload {
+		return errors.New("websocket: control message payload is too large")
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.writeFrame(messageType, data, deadline)
+}
+
+// WriteJSON 将v编码为JSON后以TextMessage发送
+func (c *Conn) WriteJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(TextMessage, data)
+}
+
+// ReadJSON 读取一个消息并按JSON解码到v
+func (c *Conn) ReadJSON(v any) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// 写一帧(调用方持有writeMu)
+func (c *Conn) writeFrame(opcode int, data []byte, deadline time.Time) error {
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if err := c.conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	var header [14]byte
+	header[0] = 0x80 | byte(opcode)
+	n := 2
+	switch length := len(data); {
+	case length <= 125:
+		header[1] = byte(length)
+	case length <= 0xffff:
+		header[1] = 126
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+		n += 2
+	default:
+		header[1] = 127
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+		n += 8
+	}
+	if !c.isServer {
+		// 客户端发送的帧需要掩码
+		header[1] |= 0x80
+		var key [4]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		copy(header[n:], key[:])
+		n += 4
+		masked := make([]byte, len(data))
+		copy(masked, data)
+		maskBytes(key, masked)
+		data = masked
+	}
+	if _, err := c.bw.Write(header[:n]); err != nil {
+		return err
+	}
+	if _, err := c.bw.Write(data); err != nil {
+		return err
+	}
+	if err := c.bw.Flush(); err != nil {
+		return err
+	}
+	if opcode == CloseMessage {
+		c.closeSent = true
+	}
+	return nil
+}
+
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i&3]
+	}
+}
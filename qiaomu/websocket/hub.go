@@ -0,0 +1,101 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+)
+
+const defaultHubWriteTimeout = 10 * time.Second
+
+// Hub 按分组管理WebSocket连接(如按聊天室、订单号分组)，用于向一组连接广播消息
+// 一个连接可以同时加入多个分组，可以被多个goroutine并发使用
+type Hub struct {
+	WriteTimeout time.Duration // 广播时每个连接的写超时时间(默认10s)，超时或写失败的连接会被关闭并移出所有分组
+	mu           sync.RWMutex
+	groups       map[string]map[*Conn]struct{}
+}
+
+// NewHub 创建Hub
+func NewHub() *Hub {
+	return &Hub{groups: make(map[string]map[*Conn]struct{})}
+}
+
+// Join 将连接加入分组
+func (h *Hub) Join(group string, conn *Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	conns, ok := h.groups[group]
+	if !ok {
+		conns = make(map[*Conn]struct{})
+		h.groups[group] = conns
+	}
+	conns[conn] = struct{}{}
+}
+
+// Leave 将连接移出分组
+func (h *Hub) Leave(group string, conn *Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.leave(group, conn)
+}
+
+// LeaveAll 将连接移出所有分组(连接断开时调用)
+func (h *Hub) LeaveAll(conn *Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for group := range h.groups {
+		h.leave(group, conn)
+	}
+}
+
+func (h *Hub) leave(group string, conn *Conn) {
+	conns, ok := h.groups[group]
+	if !ok {
+		return
+	}
+	delete(conns, conn)
+	if len(conns) == 0 {
+		delete(h.groups, group)
+	}
+}
+
+// Count 分组中的连接数
+func (h *Hub) Count(group string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.groups[group])
+}
+
+// Broadcast 向分组中的所有连接发送消息，返回发送成功的连接数
+func (h *Hub) Broadcast(group string, messageType int, data []byte) int {
+	h.mu.RLock()
+	conns := make([]*Conn, 0, len(h.groups[group]))
+	for conn := range h.groups[group] {
+		conns = append(conns, conn)
+	}
+	h.mu.RUnlock()
+
+	timeout := h.WriteTimeout
+	if timeout <= 0 {
+		timeout = defaultHubWriteTimeout
+	}
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sent := 0
+	for _, conn := range conns {
+		wg.Add(1)
+		go func(conn *Conn) {
+			defer wg.Done()
+			if err := conn.writeMessageWithDeadline(messageType, data, time.Now().Add(timeout)); err != nil {
+				h.LeaveAll(conn)
+				conn.Close()
+				return
+			}
+			mu.Lock()
+			sent++
+			mu.Unlock()
+		}(conn)
+	}
+	wg.Wait()
+	return sent
+}
@@ -0,0 +1,196 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// HandshakeError 握手失败(此时已经向客户端返回了对应的http错误)
+type HandshakeError struct {
+	Status  int
+	Message string
+}
+
+func (e HandshakeError) Error() string {
+	return "websocket: " + e.Message
+}
+
+// Upgrader 将http请求升级为WebSocket连接的配置
+type Upgrader struct {
+	HandshakeTimeout time.Duration // 握手响应的写超时时间
+	ReadBufferSize   int           // 读缓冲区大小(默认4096)
+	WriteBufferSize  int           // 写缓冲区大小(默认4096)
+	ReadLimit        int64         // 单个消息的最大字节数(0表示DefaultReadLimit，小于0表示不限制)
+	Subprotocols     []string      // 服务端支持的子协议(按客户端请求的顺序选出第一个支持的)
+	// CheckOrigin 检查请求头Origin，返回false时拒绝握手(403)；为nil时要求Origin为空或者与Host相同
+	CheckOrigin func(r *http.Request) bool
+	// PingInterval 大于0时每隔PingInterval发送一次ping，要求PongWait内收到pong(见Conn.Keepalive)
+	PingInterval time.Duration
+	PongWait     time.Duration // 默认为PingInterval的两倍
+}
+
+// Upgrade 完成握手并返回WebSocket连接，responseHeader为握手响应中额外的响应头(如Set-Cookie)
+// 握手失败时已经向客户端返回了http错误，返回HandshakeError
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request, responseHeader http.Header) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		return nil, u.returnError(w, http.StatusMethodNotAllowed, "request method is not GET")
+	}
+	if !headerContainsToken(r.Header, "Connection", "upgrade") {
+		return nil, u.returnError(w, http.StatusBadRequest, "'upgrade' token not found in 'Connection' header")
+	}
+	if !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		return nil, u.returnError(w, http.StatusBadRequest, "'websocket' token not found in 'Upgrade' header")
+	}
+	if r.Header.Get("Sec-Websocket-Version") != "13" {
+		w.Header().Set("Sec-Websocket-Version", "13")
+		return nil, u.returnError(w, http.StatusBadRequest, "unsupported version")
+	}
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = checkSameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, u.returnError(w, http.StatusForbidden, "request origin not allowed")
+	}
+	key := r.Header.Get("Sec-Websocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, u.returnError(w, http.StatusBadRequest, "'Sec-WebSocket-Key' header is invalid")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, u.returnError(w, http.StatusInternalServerError, "response does not implement http.Hijacker")
+	}
+	subprotocol := u.selectSubprotocol(r)
+
+	netConn, brw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	if brw.Reader.Buffered() > 0 {
+		netConn.Close()
+		return nil, errors.New("websocket: client sent data before handshake is complete")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: ")
+	sb.WriteString(computeAcceptKey(key))
+	sb.WriteString("\r\n")
+	if subprotocol != "" {
+		sb.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	for k, values := range responseHeader {
+		if k == "Sec-Websocket-Protocol" {
+			continue
+		}
+		for _, v := range values {
+			sb.WriteString(k + ": " + v + "\r\n")
+		}
+	}
+	sb.WriteString("\r\n")
+
+	if u.HandshakeTimeout > 0 {
+		netConn.SetWriteDeadline(time.Now().Add(u.HandshakeTimeout))
+	}
+	if _, err := netConn.Write([]byte(sb.String())); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	if u.HandshakeTimeout > 0 {
+		netConn.SetWriteDeadline(time.Time{})
+	}
+
+	br := brw.Reader
+	if u.ReadBufferSize > 0 && u.ReadBufferSize != br.Size() {
+		br = bufio.NewReaderSize(netConn, u.ReadBufferSize)
+	}
+	writeBufferSize := u.WriteBufferSize
+	if writeBufferSize <= 0 {
+		writeBufferSize = 4096
+	}
+	conn := newConn(netConn, br, bufio.NewWriterSize(netConn, writeBufferSize), true, subprotocol)
+	if u.ReadLimit != 0 {
+		conn.SetReadLimit(u.ReadLimit)
+	}
+	if u.PingInterval > 0 {
+		pongWait := u.PongWait
+		if pongWait <= 0 {
+			pongWait = 2 * u.PingInterval
+		}
+		conn.Keepalive(u.PingInterval, pongWait)
+	}
+	return conn, nil
+}
+
+func (u *Upgrader) returnError(w http.ResponseWriter, status int, message string) error {
+	http.Error(w, http.StatusText(status), status)
+	return HandshakeError{Status: status, Message: message}
+}
+
+// 按客户端请求的顺序选出第一个服务端支持的子协议
+func (u *Upgrader) selectSubprotocol(r *http.Request) string {
+	for _, protocol := range Subprotocols(r) {
+		for _, supported := range u.Subprotocols {
+			if protocol == supported {
+				return protocol
+			}
+		}
+	}
+	return ""
+}
+
+// Subprotocols 客户端请求的子协议
+func Subprotocols(r *http.Request) []string {
+	var protocols []string
+	for _, value := range r.Header.Values("Sec-Websocket-Protocol") {
+		for _, protocol := range strings.Split(value, ",") {
+			if protocol = strings.TrimSpace(protocol); protocol != "" {
+				protocols = append(protocols, protocol)
+			}
+		}
+	}
+	return protocols
+}
+
+// IsWebSocketUpgrade 请求是否是WebSocket握手请求
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return headerContainsToken(r.Header, "Connection", "upgrade") && headerContainsToken(r.Header, "Upgrade", "websocket")
+}
+
+// Origin为空或者与Host相同
+func checkSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func computeAcceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// 请求头中是否包含token(逗号分隔，忽略大小写)
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,207 @@
+package websocket
+
+import (
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T, upgrader *Upgrader, handler func(conn *Conn)) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handler(conn)
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestEcho(t *testing.T) {
+	upgrader := &Upgrader{Subprotocols: []string{"chat"}, ReadLimit: 1024}
+	url := newTestServer(t, upgrader, func(conn *Conn) {
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	})
+
+	header := http.Header{}
+	header.Set("Sec-WebSocket-Protocol", "json, chat")
+	conn, _, err := Dial(url, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if conn.Subprotocol() != "chat" {
+		t.Errorf("got subprotocol %q, want chat", conn.Subprotocol())
+	}
+
+	for _, message := range []string{"hello", strings.Repeat("x", 200), strings.Repeat("y", 1000)} {
+		if err := conn.WriteMessage(TextMessage, []byte(message)); err != nil {
+			t.Fatal(err)
+		}
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if messageType != TextMessage || string(data) != message {
+			t.Errorf("got %d %q", messageType, data)
+		}
+	}
+
+	// 服务端回复pong
+	pong := make(chan string, 1)
+	conn.SetPongHandler(func(appData string) error {
+		pong <- appData
+		return nil
+	})
+	if err := conn.WriteControl(PingMessage, []byte("ping"), time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	conn.WriteMessage(TextMessage, []byte("after ping"))
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != "after ping" {
+		t.Fatalf("got %q %v", data, err)
+	}
+	if got := <-pong; got != "ping" {
+		t.Errorf("got pong %q", got)
+	}
+
+	// 超过ReadLimit时服务端关闭连接
+	conn.WriteMessage(BinaryMessage, make([]byte, 2048))
+	if _, _, err := conn.ReadMessage(); !IsCloseError(err, CloseMessageTooBig) {
+		t.Errorf("got %v, want close 1009", err)
+	}
+}
+
+func TestDefaultReadLimit(t *testing.T) {
+	url := newTestServer(t, &Upgrader{}, func(conn *Conn) {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	for _, tt := range []struct {
+		length uint64
+		code   int
+	}{
+		{1 << 40, CloseMessageTooBig},
+		{1 << 63, CloseProtocolError},
+	} {
+		conn, _, err := Dial(url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 只发送声明了超大长度的帧头，服务端不能按声明的长度分配内存
+		header := []byte{0x80 | BinaryMessage, 0x80 | 127, 0, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3, 4}
+		binary.BigEndian.PutUint64(header[2:10], tt.length)
+		conn.NetConn().Write(header)
+		if _, _, err := conn.ReadMessage(); !IsCloseError(err, tt.code) {
+			t.Errorf("length %d: got %v, want close %d", tt.length, err, tt.code)
+		}
+		conn.Close()
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	url := newTestServer(t, &Upgrader{}, func(conn *Conn) {})
+	header := http.Header{}
+	header.Set("Origin", "http://evil.example.com")
+	_, resp, err := Dial(url, header)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("cross origin: got %v %v", resp, err)
+	}
+
+	url = newTestServer(t, &Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}, func(conn *Conn) {})
+	conn, _, err := Dial(url, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
+
+func TestKeepalive(t *testing.T) {
+	done := make(chan error, 1)
+	url := newTestServer(t, &Upgrader{PingInterval: 20 * time.Millisecond, PongWait: 50 * time.Millisecond}, func(conn *Conn) {
+		_, _, err := conn.ReadMessage()
+		done <- err
+	})
+	conn, _, err := Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 客户端在读消息时自动回复pong，连接保持
+	go conn.ReadMessage()
+	select {
+	case err := <-done:
+		t.Fatalf("connection closed while client replies pong: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	// 客户端不再回复pong，服务端读超时
+	conn.Close()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("want read error")
+		}
+	case <-time.After(time.Second):
+		t.Error("server should close connection without pong")
+	}
+}
+
+func TestHub(t *testing.T) {
+	hub := NewHub()
+	joined := make(chan struct{}, 3)
+	url := newTestServer(t, &Upgrader{}, func(conn *Conn) {
+		_, room, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		hub.Join(string(room), conn)
+		defer hub.LeaveAll(conn)
+		joined <- struct{}{}
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	var conns []*Conn
+	for _, room := range []string{"order:1", "order:1", "order:2"} {
+		conn, _, err := Dial(url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conn.WriteMessage(TextMessage, []byte(room))
+		conns = append(conns, conn)
+		<-joined
+	}
+	if hub.Count("order:1") != 2 {
+		t.Fatalf("got %d connections, want 2", hub.Count("order:1"))
+	}
+	if sent := hub.Broadcast("order:1", TextMessage, []byte("paid")); sent != 2 {
+		t.Errorf("got %d sent, want 2", sent)
+	}
+	for _, conn := range conns[:2] {
+		if _, data, err := conn.ReadMessage(); err != nil || string(data) != "paid" {
+			t.Errorf("got %q %v", data, err)
+		}
+	}
+	hub.Broadcast("order:2", TextMessage, []byte("shipped"))
+	if _, data, err := conns[2].ReadMessage(); err != nil || string(data) != "shipped" {
+		t.Errorf("got %q %v", data, err)
+	}
+}